@@ -33,6 +33,13 @@ type SVNGroupSpec struct {
 	// +kubebuilder:validation:Required
 	// The permissions that the group have.
 	Permissions []Permission `json:"permissions,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// Groups is a list of SVNGroups whose members also belong to this group.
+	// The SVNGroups must reside in the same namespace and refer to the same SVNServer as this group.
+	// A group that refers to unknown groups or to itself (directly or indirectly) is not
+	// written into the configuration of the SVNServer and is marked as Degraded.
+	Groups []GroupRef `json:"groups,omitempty"`
 }
 
 type Permission struct {
@@ -42,6 +49,14 @@ type Permission struct {
 	// The SVNRepository must reside in the same namespace as the SVNGroup.
 	Repository string `json:"repository,omitempty"`
 
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:MaxLength=1024
+	// +kubebuilder:validation:Pattern="^/[^\\[\\]\\r\\n]*$"
+	// The path inside the repository that the permission applies to (e.g. `/trunk`).
+	// It must start with `/`. Trailing slashes and `.`/`..` segments are normalized.
+	// If not specified, the permission applies to the whole repository.
+	Path string `json:"path,omitempty"`
+
 	// +kubebuilder:validation:Pattern="^(?:r|rw|)$"
 	// The permission to access to the repository.
 	Permission string `json:"permission,omitempty"`
@@ -63,12 +78,25 @@ const (
 
 // SVNGroupStatus defines the observed state of SVNGroup
 type SVNGroupStatus struct {
-	// +Kubebuilder:validation:Optional
-	Conditions []Condition `json:"conditions"`
+	// +kubebuilder:validation:Optional
+	// +listType=map
+	// +listMapKey=type
+	// +patchMergeKey=type
+	// +patchStrategy=merge
+	// Conditions are the latest observations of the SVNGroup, keyed by type.
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+
+	// +kubebuilder:validation:Optional
+	// ObservedGeneration is the generation of the SVNGroup that the status reflects.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
 }
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Server",type=string,JSONPath=`.spec.svnServer`
+//+kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+//+kubebuilder:printcolumn:name="Reason",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].reason`,priority=1
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // SVNGroup is the Schema for the svngroups API
 type SVNGroup struct {
@@ -0,0 +1,136 @@
+/*
+Copyright 2024 markzhang.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// SetupWebhookWithManager registers the validating webhook of SVNGroups.
+func (r *SVNGroup) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		WithValidator(&SVNGroupValidator{Client: mgr.GetAPIReader()}).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/validate-svn-zhangyi-chat-v1alpha1-svngroup,mutating=false,failurePolicy=fail,sideEffects=None,groups=svn.zhangyi.chat,resources=svngroups,verbs=create;update,versions=v1alpha1,name=vsvngroup.kb.io,admissionReviewVersions=v1
+
+// SVNGroupValidator validates SVNGroups.
+//
+// +kubebuilder:object:generate=false
+type SVNGroupValidator struct {
+	Client client.Reader
+}
+
+var _ admission.CustomValidator = &SVNGroupValidator{}
+
+// ValidateCreate rejects SVNGroups of unknown SVNServers and SVNGroups with invalid references.
+func (v *SVNGroupValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	g, ok := obj.(*SVNGroup)
+	if !ok {
+		return nil, fmt.Errorf("expected an SVNGroup but got %T", obj)
+	}
+	fieldErr, err := validateSVNServerExists(ctx, v.Client, g.Namespace, g.Spec.SVNServer)
+	if err != nil {
+		return nil, err
+	}
+	if fieldErr != nil {
+		return nil, invalidOrNil("SVNGroup", g.Name, field.ErrorList{fieldErr})
+	}
+	errs, err := v.validateReferences(ctx, g)
+	if err != nil {
+		return nil, err
+	}
+	return nil, invalidOrNil("SVNGroup", g.Name, errs)
+}
+
+// ValidateUpdate rejects changes to spec.svnServer and SVNGroups with invalid references.
+func (v *SVNGroupValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldGroup, ok := oldObj.(*SVNGroup)
+	if !ok {
+		return nil, fmt.Errorf("expected an SVNGroup but got %T", oldObj)
+	}
+	g, ok := newObj.(*SVNGroup)
+	if !ok {
+		return nil, fmt.Errorf("expected an SVNGroup but got %T", newObj)
+	}
+	if specUnchanged(oldGroup.Spec, g.Spec) {
+		return nil, nil
+	}
+	if fieldErr := validateSVNServerUnchanged(oldGroup.Spec.SVNServer, g.Spec.SVNServer); fieldErr != nil {
+		return nil, invalidOrNil("SVNGroup", g.Name, field.ErrorList{fieldErr})
+	}
+	errs, err := v.validateReferences(ctx, g)
+	if err != nil {
+		return nil, err
+	}
+	return nil, invalidOrNil("SVNGroup", g.Name, errs)
+}
+
+// ValidateDelete does nothing because deletions are not validated.
+// SVNGroups and SVNUsers that refer to the deleted SVNGroup are marked as Degraded by the controller.
+func (v *SVNGroupValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validateReferences checks the permissions and the member groups of g.
+func (v *SVNGroupValidator) validateReferences(ctx context.Context, g *SVNGroup) (field.ErrorList, error) {
+	errs, err := validatePermissions(ctx, v.Client, g.Namespace, g.Spec.SVNServer, g.Spec.Permissions)
+	if err != nil {
+		return nil, err
+	}
+	groups, err := listGroups(ctx, v.Client, g.Namespace, g.Spec.SVNServer)
+	if err != nil {
+		return nil, err
+	}
+	groups[g.Name] = g
+	errs = append(errs, validateGroupRefs(g.Spec.Groups, groups)...)
+	if containsGroup(groups, g.Spec.Groups, g.Name) {
+		errs = append(errs, field.Forbidden(field.NewPath("spec", "groups"),
+			"SVNGroup must not contain itself directly or indirectly"))
+	}
+	return errs, nil
+}
+
+// containsGroup reports whether name is reachable from refs through the member groups.
+func containsGroup(groups map[string]*SVNGroup, refs []GroupRef, name string) bool {
+	visited := map[string]bool{}
+	queue := append([]GroupRef{}, refs...)
+	for len(queue) > 0 {
+		ref := queue[0]
+		queue = queue[1:]
+		if ref.Name == name {
+			return true
+		}
+		if visited[ref.Name] {
+			continue
+		}
+		visited[ref.Name] = true
+		if sub, ok := groups[ref.Name]; ok {
+			queue = append(queue, sub.Spec.Groups...)
+		}
+	}
+	return false
+}
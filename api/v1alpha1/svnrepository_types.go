@@ -17,6 +17,8 @@ limitations under the License.
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -30,21 +32,213 @@ type SVNRepositorySpec struct {
 
 	// The name of the SVNServer
 	SVNServer string `json:"svnServer,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=private;anonymousRead;authenticatedRead;authenticatedWrite
+	// Access is the access level for users that are not given any permissions by SVNGroups or SVNUsers.
+	// If not specified, `private` is used.
+	//
+	//   - private: only users that are explicitly given permissions can access to the repository.
+	//   - anonymousRead: everyone, including anonymous users, can read from the repository.
+	//   - authenticatedRead: all authenticated users can read from the repository.
+	//   - authenticatedWrite: all authenticated users can both read from and write to the repository.
+	Access string `json:"access,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=Retain;Archive;Delete
+	// DeletionPolicy specifies what happens to the actual repository when the SVNRepository is deleted.
+	// If not specified, `Retain` is used.
+	//
+	//   - Retain: the repository is left as is. It can be restored by recreating the SVNRepository.
+	//   - Archive: the repository is moved into the archive directory on the same volume with a timestamp.
+	//   - Delete: the repository is deleted permanently.
+	DeletionPolicy string `json:"deletionPolicy,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// InitialLayout is a set of directories that are committed as the first revision
+	// right after the repository is created.
+	// This has no effect on repositories that already exist.
+	InitialLayout *InitialLayout `json:"initialLayout,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// Source is a dump file created by `svnadmin dump` that is loaded into the repository
+	// right after the repository is created. InitialLayout is ignored if Source is specified.
+	// This has no effect on repositories that already exist.
+	// ConfigMaps, Secrets and PersistentVolumeClaims are mounted on the pod of the SVNServer
+	// as long as they are specified here, so the pod is restarted when Source is changed.
+	Source *RepositorySource `json:"source,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// HTTP overrides the configuration of Apache for the repository, which is served over HTTP(S)
+	// with that of the SVNServer otherwise. This has no effect on the svn:// protocol.
+	HTTP *RepositoryHTTPSpec `json:"http,omitempty"`
+}
+
+// RepositoryHTTPSpec is a configuration of Apache for a specific repository.
+type RepositoryHTTPSpec struct {
+	// +kubebuilder:validation:Optional
+	// Autoversioning lets WebDAV clients that are not aware of Subversion, e.g. file managers,
+	// commit to the repository. Each change of such clients is committed as a revision.
+	Autoversioning bool `json:"autoversioning,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// LimitRequestBody is the maximum size of the bodies of requests, e.g. `100Mi`, which limits the size of commits.
+	// `0` means unlimited. It must not exceed 2Gi.
+	LimitRequestBody *resource.Quantity `json:"limitRequestBody,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Pattern=`^[^"\\\s]+$`
+	// IndexXSLT is a URL of an XSL transformation that formats directory listings in web browsers, e.g. `/svnindex.xsl`.
+	IndexXSLT string `json:"indexXSLT,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// +listType=set
+	// AllowedSourceRanges are IP addresses and CIDRs that clients must connect from, e.g. `10.0.0.0/8`.
+	// Clients from the other addresses are denied, including anonymous users and authenticated users.
+	// Note that clients are seen from the address of the ingress controller or the load balancer if they proxy requests.
+	AllowedSourceRanges []string `json:"allowedSourceRanges,omitempty"`
+}
+
+// MaxLimitRequestBody is the maximum value of LimitRequestBody that Apache accepts.
+const MaxLimitRequestBody = 2147483647
+
+// RepositorySource is a location of a dump file. Exactly one of the fields must be specified.
+//
+// +kubebuilder:validation:MinProperties=1
+// +kubebuilder:validation:MaxProperties=1
+type RepositorySource struct {
+	// +kubebuilder:validation:Optional
+	// PersistentVolumeClaim specifies a file on a PersistentVolumeClaim.
+	// The PersistentVolumeClaim is mounted read-only on the pod of the SVNServer,
+	// so it must be mountable on the same node.
+	PersistentVolumeClaim *PersistentVolumeClaimSource `json:"persistentVolumeClaim,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// ConfigMap selects a key of a ConfigMap in the same namespace.
+	ConfigMap *corev1.ConfigMapKeySelector `json:"configMap,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// Secret selects a key of a Secret in the same namespace.
+	Secret *corev1.SecretKeySelector `json:"secret,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Pattern="^https?://"
+	// URL is an HTTP or HTTPS URL to download the dump file from.
+	URL string `json:"url,omitempty"`
+}
+
+// PersistentVolumeClaimSource is a file on a PersistentVolumeClaim.
+type PersistentVolumeClaimSource struct {
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	// ClaimName is the name of the PersistentVolumeClaim in the same namespace.
+	ClaimName string `json:"claimName"`
+
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	// Path is a path to the dump file relative to the root of the volume.
+	Path string `json:"path"`
 }
 
+// InitialLayout is a set of directories that new repositories initially have.
+type InitialLayout struct {
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=standard
+	// Preset is a predefined set of directories.
+	//
+	//   - standard: `trunk`, `branches` and `tags`.
+	Preset string `json:"preset,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// Directories is a list of paths of directories relative to the root of the repository (e.g. `branches/qa`).
+	// Each path consists of alphanumeric characters, `_`, `-` and `.`, and no path segment can start with `.`.
+	// Invalid paths are rejected by the validating webhook. Parent directories are created as needed.
+	// If Preset is also specified, these directories are created in addition to those of the preset.
+	Directories []string `json:"directories,omitempty"`
+}
+
+// Here is a list of allowed presets of initial layouts.
+const (
+	// InitialLayoutPresetStandard is the standard layout that consists of trunk, branches and tags.
+	InitialLayoutPresetStandard = "standard"
+)
+
+// Here is a list of allowed access levels.
+const (
+	// AccessPrivate means only users that are explicitly given permissions can access to the repository.
+	AccessPrivate = "private"
+
+	// AccessAnonymousRead means everyone, including anonymous users, can read from the repository.
+	AccessAnonymousRead = "anonymousRead"
+
+	// AccessAuthenticatedRead means all authenticated users can read from the repository.
+	AccessAuthenticatedRead = "authenticatedRead"
+
+	// AccessAuthenticatedWrite means all authenticated users can both read from and write to the repository.
+	AccessAuthenticatedWrite = "authenticatedWrite"
+)
+
+// Here is a list of allowed deletion policies.
+const (
+	// DeletionPolicyRetain means the repository is left as is when the SVNRepository is deleted.
+	DeletionPolicyRetain = "Retain"
+
+	// DeletionPolicyArchive means the repository is moved into the archive directory when the SVNRepository is deleted.
+	DeletionPolicyArchive = "Archive"
+
+	// DeletionPolicyDelete means the repository is deleted when the SVNRepository is deleted.
+	DeletionPolicyDelete = "Delete"
+)
+
 // SVNRepositoryStatus defines the observed state of SVNRepository
 type SVNRepositoryStatus struct {
-	// +Kubebuilder:validation:Optional
-	Conditions []Condition `json:"conditions"`
+	// +kubebuilder:validation:Optional
+	// +listType=map
+	// +listMapKey=type
+	// +patchMergeKey=type
+	// +patchStrategy=merge
+	// Conditions are the latest observations of the SVNRepository, keyed by type.
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+
+	// +kubebuilder:validation:Optional
+	// ObservedGeneration is the generation of the SVNRepository that the status reflects.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// Load is the progress of loading `spec.source` into the repository.
+	Load *LoadStatus `json:"load,omitempty"`
 }
 
+// LoadStatus is the progress of loading a dump file into a repository.
+type LoadStatus struct {
+	// Phase is one of `Loading`, `Completed` and `Failed`.
+	Phase string `json:"phase,omitempty"`
+
+	// Revision is the latest revision that has been loaded.
+	Revision int64 `json:"revision,omitempty"`
+
+	// Message is a human-readable message why loading the dump failed.
+	Message string `json:"message,omitempty"`
+}
+
+// Here is a list of phases of loading dump files.
+const (
+	LoadPhaseLoading   = "Loading"
+	LoadPhaseCompleted = "Completed"
+	LoadPhaseFailed    = "Failed"
+)
+
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Server",type=string,JSONPath=`.spec.svnServer`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Reason",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].reason`,priority=1
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // SVNRepository is the Schema for the svnrepositories API
 //
-// The svn-operator does not delete actual repositories if SVNRepository resources are deleted. In such case, you can restore repositories by recreating SVNRepository resources.
-// Currently we do not provide methods to configure this behavior.
+// By default, the svn-operator does not delete actual repositories if SVNRepository resources are deleted. In such case, you can restore repositories by recreating SVNRepository resources.
+// This behavior can be configured by `spec.deletionPolicy`.
 type SVNRepository struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
@@ -0,0 +1,130 @@
+/*
+Copyright 2024 markzhang.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/markzhang0928/svn-operator/pkg/svnconfig"
+)
+
+// SetupWebhookWithManager registers the validating webhook of SVNRepositories.
+func (r *SVNRepository) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		WithValidator(&SVNRepositoryValidator{Client: mgr.GetAPIReader()}).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/validate-svn-zhangyi-chat-v1alpha1-svnrepository,mutating=false,failurePolicy=fail,sideEffects=None,groups=svn.zhangyi.chat,resources=svnrepositories,verbs=create;update,versions=v1alpha1,name=vsvnrepository.kb.io,admissionReviewVersions=v1
+
+// SVNRepositoryValidator validates SVNRepositories.
+//
+// +kubebuilder:object:generate=false
+type SVNRepositoryValidator struct {
+	Client client.Reader
+}
+
+var _ admission.CustomValidator = &SVNRepositoryValidator{}
+
+// ValidateCreate rejects SVNRepositories of unknown SVNServers and invalid initial layouts.
+func (v *SVNRepositoryValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	r, ok := obj.(*SVNRepository)
+	if !ok {
+		return nil, fmt.Errorf("expected an SVNRepository but got %T", obj)
+	}
+	errs := validateInitialLayout(r.Spec.InitialLayout)
+	errs = append(errs, validateRepositoryHTTP(r.Spec.HTTP)...)
+	fieldErr, err := validateSVNServerExists(ctx, v.Client, r.Namespace, r.Spec.SVNServer)
+	if err != nil {
+		return nil, err
+	}
+	if fieldErr != nil {
+		errs = append(errs, fieldErr)
+	}
+	return nil, invalidOrNil("SVNRepository", r.Name, errs)
+}
+
+// ValidateUpdate rejects changes to spec.svnServer and invalid initial layouts.
+func (v *SVNRepositoryValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldRepo, ok := oldObj.(*SVNRepository)
+	if !ok {
+		return nil, fmt.Errorf("expected an SVNRepository but got %T", oldObj)
+	}
+	r, ok := newObj.(*SVNRepository)
+	if !ok {
+		return nil, fmt.Errorf("expected an SVNRepository but got %T", newObj)
+	}
+	if specUnchanged(oldRepo.Spec, r.Spec) {
+		return nil, nil
+	}
+	errs := validateInitialLayout(r.Spec.InitialLayout)
+	errs = append(errs, validateRepositoryHTTP(r.Spec.HTTP)...)
+	if fieldErr := validateSVNServerUnchanged(oldRepo.Spec.SVNServer, r.Spec.SVNServer); fieldErr != nil {
+		errs = append(errs, fieldErr)
+	}
+	return nil, invalidOrNil("SVNRepository", r.Name, errs)
+}
+
+// ValidateDelete does nothing because deletions are not validated.
+func (v *SVNRepositoryValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validateInitialLayout rejects directories that the controller would ignore.
+func validateInitialLayout(l *InitialLayout) field.ErrorList {
+	var errs field.ErrorList
+	if l == nil {
+		return errs
+	}
+	for i, d := range l.Directories {
+		if !svnconfig.IsValidLayoutDirectory(d) {
+			errs = append(errs, field.Invalid(field.NewPath("spec", "initialLayout", "directories").Index(i), d,
+				"must be a relative path whose segments consist of alphanumeric characters, `_`, `-` and `.` and do not start with `.`"))
+		}
+	}
+	return errs
+}
+
+// validateRepositoryHTTP checks the values that are rendered into the configuration of Apache as they are.
+func validateRepositoryHTTP(h *RepositoryHTTPSpec) field.ErrorList {
+	var errs field.ErrorList
+	if h == nil {
+		return errs
+	}
+	fldPath := field.NewPath("spec", "http")
+	if q := h.LimitRequestBody; q != nil && (q.Sign() < 0 || q.Value() > MaxLimitRequestBody) {
+		errs = append(errs, field.Invalid(fldPath.Child("limitRequestBody"), q.String(),
+			fmt.Sprintf("must be between 0 and %d bytes", MaxLimitRequestBody)))
+	}
+	for i, r := range h.AllowedSourceRanges {
+		if net.ParseIP(r) == nil {
+			if _, _, err := net.ParseCIDR(r); err != nil {
+				errs = append(errs, field.Invalid(fldPath.Child("allowedSourceRanges").Index(i), r, "must be an IP address or a CIDR"))
+			}
+		}
+	}
+	return errs
+}
@@ -18,6 +18,7 @@ package v1alpha1
 
 import (
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -30,16 +31,405 @@ type SVNServerSpec struct {
 	// PodTemplate is a template to create Pods.
 	PodTemplate PodTemplate `json:"podTemplate,omitempty"`
 
-	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Optional
 	// VolumeClaimTemplate is a PVC to store SVN repositories and configuration files in.
+	// If the access modes, the storage request or the storage class are not specified,
+	// the defaults of the operator are written into this field when the SVNServer is created.
+	// This field cannot be changed after creation.
 	VolumeClaimTemplate corev1.PersistentVolumeClaim `json:"volumeClaimTemplate,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// Service configures the client-facing Service named `<name of the SVNServer>-client`.
+	// The Service is created even if this field is not specified, as a ClusterIP Service that exposes HTTP on port 80,
+	// HTTPS on port 443 if TLS is specified, and svn:// on port 3690 if Protocols contains svn.
+	// It is separate from the headless Service of the StatefulSet, which is named after the SVNServer.
+	Service *ServiceSpec `json:"service,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:MinItems=1
+	// +listType=set
+	// Protocols are the protocols that the SVN server serves the repositories with, which are http and svn.
+	// If not specified, only http is served.
+	//
+	//   - http: Subversion over HTTP by Apache and mod_dav_svn on port 80, and over HTTPS on port 443 if TLS is specified.
+	//   - svn: the `svn://` protocol by svnserve on port 3690. svnserve requires plaintext passwords,
+	//     so only SVNUsers whose passwords are plaintext in Secrets, including generated ones, can be authenticated.
+	Protocols []string `json:"protocols,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// Authentication configures how the SVN server authenticates users.
+	// If not specified, users are authenticated with the passwords of SVNUsers.
+	Authentication *AuthenticationSpec `json:"authentication,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=Volume;API
+	// +kubebuilder:default=Volume
+	// ConfigSync is the way changes to the configuration, e.g. permissions and passwords, reach the SVN server pods.
+	//
+	//   - Volume: the ConfigMap and the Secret of the SVNServer are mounted as volumes, which kubelet syncs periodically.
+	//     Changes may take a minute or more to take effect.
+	//   - API: the server updater watches the ConfigMap and the Secret through the Kubernetes API and applies changes
+	//     immediately. The operator grants the ServiceAccount of the pods read access to them with a Role and
+	//     a RoleBinding named after the SVNServer.
+	ConfigSync string `json:"configSync,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// Apache tunes Apache and mod_dav_svn, which serve the repositories over HTTP.
+	Apache *ApacheSpec `json:"apache,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// TLS makes the SVN server itself serve HTTPS on port 443 for end-to-end encryption,
+	// e.g. in clusters without ingress controllers.
+	TLS *TLSSpec `json:"tls,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// Ingress exposes the repositories at the URL prefix (`/repos/` by default) of a host through an Ingress named after the SVNServer.
+	// This cannot be specified together with HTTPRoute.
+	Ingress *IngressSpec `json:"ingress,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// HTTPRoute exposes the repositories at the URL prefix (`/repos/` by default) of a host through an HTTPRoute of the Gateway API
+	// named after the SVNServer. The Gateway API CRDs must be installed in the cluster.
+	// This cannot be specified together with Ingress.
+	HTTPRoute *HTTPRouteSpec `json:"httpRoute,omitempty"`
+}
+
+// ApacheSpec is a configuration of Apache and mod_dav_svn.
+// Fields that are not specified keep the defaults of the SVN server image.
+type ApacheSpec struct {
+	// +kubebuilder:validation:Optional
+	// ServerName is the host name that Apache identifies itself with, e.g. in redirects.
+	// If not specified, the host of Ingress or HTTPRoute is used, or the DNS name of the client-facing Service.
+	ServerName string `json:"serverName,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Pattern=`^/([A-Za-z0-9._~-]+/)+$`
+	// URLPrefix is the URL path that the repositories are served under, which must start and end with `/`.
+	// It cannot be `/`, since Apache is probed with `GET /` outside of it.
+	// If not specified, the repositories are served under `/repos/`.
+	URLPrefix string `json:"urlPrefix,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=1
+	// TimeoutSeconds is the number of seconds that Apache waits for I/O, which is 300 by default.
+	// Increase it if large commits or checkouts time out.
+	TimeoutSeconds int32 `json:"timeoutSeconds,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// KeepAlive enables persistent connections, which is enabled by default.
+	KeepAlive *bool `json:"keepAlive,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=1
+	// KeepAliveTimeoutSeconds is the number of seconds that Apache waits for the next request
+	// on a persistent connection, which is 5 by default.
+	KeepAliveTimeoutSeconds int32 `json:"keepAliveTimeoutSeconds,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// ListParentPath lets users list the repositories by accessing the URL prefix.
+	ListParentPath bool `json:"listParentPath,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=9
+	// CompressionLevel is the level from 0 (no compression) to 9 of compression of the data sent to clients.
+	// Lower levels save CPU on fast networks.
+	CompressionLevel *int32 `json:"compressionLevel,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// InMemoryCacheSize is the size of the cache of mod_dav_svn per Apache process, e.g. `64Mi`. `0` disables the cache.
+	InMemoryCacheSize *resource.Quantity `json:"inMemoryCacheSize,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// LimitXMLRequestBody is the maximum size of the XML bodies of requests, e.g. `1Mi`. `0` means unlimited,
+	// which may be required to commit many files at once.
+	LimitXMLRequestBody *resource.Quantity `json:"limitXMLRequestBody,omitempty"`
+}
+
+// URLPrefix returns the URL path that the repositories are served under.
+func (s *SVNServerSpec) URLPrefix() string {
+	if s.Apache != nil && s.Apache.URLPrefix != "" {
+		return s.Apache.URLPrefix
+	}
+	return DefaultURLPrefix
+}
+
+// TLSSpec is the TLS configuration of the SVN server.
+type TLSSpec struct {
+	// +kubebuilder:validation:Required
+	// SecretName is the name of the TLS Secret (`kubernetes.io/tls`) that contains the certificate and the key
+	// of the SVN server in `tls.crt` and `tls.key`. The Secret must be in the namespace of the SVNServer.
+	// Apache reads them on startup, so the pods must be restarted to use a renewed certificate.
+	SecretName string `json:"secretName"`
+
+	// +kubebuilder:validation:Optional
+	// RedirectHTTP makes the SVN server redirect HTTP requests to HTTPS instead of serving the repositories on HTTP.
+	// This cannot be enabled together with Ingress or HTTPRoute, which route to the HTTP port.
+	RedirectHTTP bool `json:"redirectHTTP,omitempty"`
+}
+
+// Here is a list of protocols that SVN servers serve repositories with.
+const (
+	// ProtocolHTTP is Subversion over HTTP(S) served by Apache and mod_dav_svn.
+	ProtocolHTTP = "http"
+	// ProtocolSVN is the svn:// protocol served by svnserve.
+	ProtocolSVN = "svn"
+)
+
+// ServesProtocol reports whether the SVN server serves the repositories with the protocol.
+func (s *SVNServerSpec) ServesProtocol(protocol string) bool {
+	if len(s.Protocols) == 0 {
+		return protocol == ProtocolHTTP
+	}
+	for _, p := range s.Protocols {
+		if p == protocol {
+			return true
+		}
+	}
+	return false
+}
+
+// Here is a list of ways that configurations are synced to SVN server pods.
+const (
+	// ConfigSyncVolume syncs configurations through volumes of the ConfigMap and the Secret.
+	ConfigSyncVolume = "Volume"
+	// ConfigSyncAPI syncs configurations by watching the ConfigMap and the Secret through the Kubernetes API.
+	ConfigSyncAPI = "API"
+)
+
+// AuthenticationSpec is a configuration of authentication of an SVNServer.
+type AuthenticationSpec struct {
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=File;LDAP
+	// +kubebuilder:default=File
+	// Mode is the way the SVN server authenticates users.
+	//
+	//   - File: users are authenticated with the passwords of SVNUsers.
+	//   - LDAP: users are authenticated with an LDAP directory by mod_authnz_ldap. SVNUsers need no passwords and
+	//     only name LDAP users, so that SVNGroups and permissions apply to them. svn:// cannot be served in this mode.
+	Mode string `json:"mode,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// LDAP is the LDAP directory to authenticate users with. This must be specified if Mode is `LDAP`.
+	LDAP *LDAPSpec `json:"ldap,omitempty"`
+}
+
+// Here is a list of authentication modes of SVNServers.
+const (
+	// AuthenticationModeFile authenticates users with the passwords of SVNUsers.
+	AuthenticationModeFile = "File"
+	// AuthenticationModeLDAP authenticates users with an LDAP directory.
+	AuthenticationModeLDAP = "LDAP"
+)
+
+// LDAPSpec is a configuration of an LDAP directory.
+type LDAPSpec struct {
+	// +kubebuilder:validation:Required
+	// URL is the URL of the LDAP server followed by the base DN to search users in,
+	// e.g. `ldaps://ldap.example.com/ou=people,dc=example,dc=com`.
+	URL string `json:"url"`
+
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default=uid
+	// UserAttribute is the attribute that usernames are matched against.
+	// The usernames are the names of SVNUsers.
+	UserAttribute string `json:"userAttribute,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// UserFilter is an LDAP filter that users must match, e.g. `(memberOf=cn=svn,ou=groups,dc=example,dc=com)`.
+	UserFilter string `json:"userFilter,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// StartTLS upgrades connections to `ldap://` URLs with STARTTLS.
+	StartTLS bool `json:"startTLS,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// BindSecretName is the name of a Secret that contains the DN to bind as in `bindDN`
+	// and its password in `bindPassword`. If not specified, the SVN server binds anonymously to search users.
+	BindSecretName string `json:"bindSecretName,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// CASecretName is the name of a Secret that contains PEM-encoded CA certificates in `ca.crt`,
+	// which verify the certificate of the LDAP server.
+	CASecretName string `json:"caSecretName,omitempty"`
+}
+
+// Here is a list of keys of Secrets referred to by LDAPSpec.
+const (
+	LDAPSecretKeyBindDN       = "bindDN"
+	LDAPSecretKeyBindPassword = "bindPassword"
+	LDAPSecretKeyCACert       = "ca.crt"
+)
+
+// LDAP returns the LDAP directory to authenticate users with, or nil if users are authenticated with passwords.
+func (s *SVNServerSpec) LDAP() *LDAPSpec {
+	if s.Authentication == nil || s.Authentication.Mode != AuthenticationModeLDAP {
+		return nil
+	}
+	return s.Authentication.LDAP
+}
+
+// IngressSpec is a template of the Ingress of an SVNServer.
+type IngressSpec struct {
+	// +kubebuilder:validation:Required
+	// Host is the fully qualified domain name that clients access the SVN server with.
+	Host string `json:"host"`
+
+	// +kubebuilder:validation:Optional
+	// IngressClassName is the name of the IngressClass that implements the Ingress.
+	// If not specified, the default IngressClass of the cluster is used.
+	IngressClassName string `json:"ingressClassName,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// Annotations are added to the Ingress, e.g. to configure the ingress controller.
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// TLS terminates HTTPS at the Ingress.
+	// It is strongly recommended since the SVN server authenticates users with basic auth.
+	TLS *IngressTLS `json:"tls,omitempty"`
+}
+
+// IngressTLS is the TLS configuration of the Ingress of an SVNServer.
+// At least one of SecretName and IssuerRef must be specified.
+type IngressTLS struct {
+	// +kubebuilder:validation:Optional
+	// SecretName is the name of the TLS Secret that contains the certificate of the host.
+	// If IssuerRef is specified, cert-manager issues the certificate into this Secret,
+	// which defaults to `<name of the SVNServer>-tls`.
+	SecretName string `json:"secretName,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// IssuerRef is the cert-manager issuer of the certificate.
+	// If specified, the Ingress is annotated so that cert-manager issues the certificate.
+	IssuerRef *IssuerRef `json:"issuerRef,omitempty"`
+}
+
+// IssuerRef refers to an Issuer or a ClusterIssuer of cert-manager.
+type IssuerRef struct {
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=Issuer;ClusterIssuer
+	// +kubebuilder:default=Issuer
+	// Kind is the kind of the issuer.
+	Kind string `json:"kind,omitempty"`
+
+	// +kubebuilder:validation:Required
+	// Name is the name of the issuer. Issuers must be in the namespace of the SVNServer.
+	Name string `json:"name"`
 }
 
+// HTTPRouteSpec is a template of the HTTPRoute of an SVNServer.
+type HTTPRouteSpec struct {
+	// +kubebuilder:validation:Required
+	// Host is the fully qualified domain name that clients access the SVN server with.
+	Host string `json:"host"`
+
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinItems=1
+	// ParentRefs are the Gateways that the HTTPRoute is attached to.
+	ParentRefs []GatewayRef `json:"parentRefs"`
+
+	// +kubebuilder:validation:Optional
+	// Annotations are added to the HTTPRoute.
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// HTTPS means that the listeners of the Gateways terminate TLS for the host.
+	// TLS is configured on the Gateways; this field is only used to report the external URL.
+	HTTPS bool `json:"https,omitempty"`
+}
+
+// GatewayRef refers to a Gateway of the Gateway API.
+type GatewayRef struct {
+	// +kubebuilder:validation:Required
+	// Name is the name of the Gateway.
+	Name string `json:"name"`
+
+	// +kubebuilder:validation:Optional
+	// Namespace is the namespace of the Gateway.
+	// If not specified, the namespace of the SVNServer is used.
+	Namespace string `json:"namespace,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// SectionName is the name of the listener of the Gateway to attach to.
+	// If not specified, the HTTPRoute is attached to all listeners that allow it.
+	SectionName string `json:"sectionName,omitempty"`
+}
+
+// ServiceSpec is a template of the client-facing Service of an SVNServer.
+type ServiceSpec struct {
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=ClusterIP;NodePort;LoadBalancer
+	// +kubebuilder:default=ClusterIP
+	// Type is the type of the Service.
+	Type string `json:"type,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// +listType=map
+	// +listMapKey=name
+	// Ports are the ports that the Service exposes.
+	// If not specified, the ports of all protocols that the SVN server serves are exposed on their default port numbers.
+	Ports []ServicePort `json:"ports,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// Annotations are added to the Service, e.g. to configure load balancers of cloud providers.
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// LoadBalancerSourceRanges restricts the client IPs that can access the load balancer (e.g. `10.0.0.0/8`).
+	// This can only be specified if Type is `LoadBalancer`.
+	LoadBalancerSourceRanges []string `json:"loadBalancerSourceRanges,omitempty"`
+}
+
+// ServicePort is a port of the client-facing Service.
+type ServicePort struct {
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Enum=http;https;svn
+	// Name is the name of the port of the SVN server to expose.
+	//
+	//   - http: Subversion over HTTP (mod_dav_svn).
+	//   - https: Subversion over HTTPS. This can only be exposed if spec.tls is specified.
+	//   - svn: the svn:// protocol (svnserve). This can only be exposed if spec.protocols contains svn.
+	Name string `json:"name"`
+
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// Port is the port number of the Service.
+	Port int32 `json:"port"`
+
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// NodePort is the port on each node if Type is `NodePort` or `LoadBalancer`.
+	// If not specified, the port is allocated by Kubernetes.
+	NodePort int32 `json:"nodePort,omitempty"`
+}
+
+// Here is a list of names of ports of SVN servers.
+const (
+	// PortNameHTTP is the port that serves Subversion over HTTP.
+	PortNameHTTP = "http"
+	// PortNameHTTPS is the port that serves Subversion over HTTPS.
+	PortNameHTTPS = "https"
+	// PortNameSVN is the port that serves the svn:// protocol.
+	PortNameSVN = "svn"
+)
+
+// ClientServiceSuffix is a suffix of names of the client-facing Services of SVNServers.
+const ClientServiceSuffix = "-client"
+
+// TLSSecretSuffix is a suffix of names of the TLS Secrets that cert-manager issues certificates into by default.
+const TLSSecretSuffix = "-tls"
+
+// DefaultURLPrefix is the URL path that the SVN server serves repositories under by default.
+const DefaultURLPrefix = "/repos/"
+
 // PodTemplate is an optional template to create SVN server pods.
 type PodTemplate struct {
 	// +kubebuilder:validation:Optional
 	// Image specifies a container image of SVN server.
-	// If not specified, the default value will be used.
+	// If not specified, the default of the operator is written into this field.
 	Image string `json:"image,omitempty"`
 
 	// +kubebuilder:validation:Optional
@@ -48,9 +438,10 @@ type PodTemplate struct {
 	// More info: https://kubernetes.io/docs/concepts/configuration/assign-pod-node/
 	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
 
-	// +kubebuilder:validation:Required
-	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:Optional
 	// ServiceAccountName is the name of the ServiceAccount to use to run this pod.
+	// If not specified, the default of the operator is written into this field,
+	// or the `default` ServiceAccount of the namespace is used if the operator has no default.
 	// More info: https://kubernetes.io/docs/tasks/configure-pod-container/configure-service-account/
 	ServiceAccountName string `json:"serviceAccountName,omitempty"`
 
@@ -68,33 +459,172 @@ type PodTemplate struct {
 	// +kubebuilder:validation:Optional
 	// If specified, the pod's tolerations.
 	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
-}
 
-type Condition struct {
-	Type ConditionType `json:"type"`
+	// +kubebuilder:validation:Optional
+	// Annotations are added to the pods, e.g. to configure log shipping or service meshes.
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// TopologySpreadConstraints describes how the pods ought to spread across topology domains.
+	// More info: https://kubernetes.io/docs/concepts/scheduling-eviction/topology-spread-constraints/
+	TopologySpreadConstraints []corev1.TopologySpreadConstraint `json:"topologySpreadConstraints,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// PriorityClassName is the name of the PriorityClass of the pods.
+	// More info: https://kubernetes.io/docs/concepts/scheduling-eviction/pod-priority-preemption/
+	PriorityClassName string `json:"priorityClassName,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// SecurityContext holds pod-level security attributes, e.g. to run the pods as non-root.
+	// More info: https://kubernetes.io/docs/tasks/configure-pod-container/security-context/
+	SecurityContext *corev1.PodSecurityContext `json:"securityContext,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// Resources are the compute resources of the SVN server container.
+	// More info: https://kubernetes.io/docs/concepts/configuration/manage-resources-containers/
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// ContainerSecurityContext holds security attributes of the SVN server container,
+	// which override those in SecurityContext.
+	ContainerSecurityContext *corev1.SecurityContext `json:"containerSecurityContext,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// +listType=map
+	// +listMapKey=name
+	// Env are environment variables added to the SVN server container.
+	// Names starting with `SVN_` are reserved for the operator.
+	Env []corev1.EnvVar `json:"env,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// +listType=map
+	// +listMapKey=name
+	// Volumes are volumes added to the pods, which can be mounted by VolumeMounts and Sidecars.
+	// Their names must not be used by the operator: `repos`, `config`, `auth`, `tls`, `ldap-bind`, `ldap-ca`
+	// and names starting with `source-`.
+	Volumes []corev1.Volume `json:"volumes,omitempty"`
 
-	Reason string `json:"reason,omitempty"`
+	// +kubebuilder:validation:Optional
+	// VolumeMounts are volumes mounted into the SVN server container in addition to those of the operator.
+	VolumeMounts []corev1.VolumeMount `json:"volumeMounts,omitempty"`
 
-	// The time when the SVNServer's condition changed in RFC3339 format.
-	TransitionTime string `json:"transitionTime"`
+	// +kubebuilder:validation:Optional
+	// +listType=map
+	// +listMapKey=name
+	// Sidecars are containers that run alongside the SVN server container, e.g. to ship logs.
+	// They must not be named `svn`.
+	Sidecars []corev1.Container `json:"sidecars,omitempty"`
 }
 
-type ConditionType string
+// Names of the volumes that the operator adds to SVN server pods.
+// PodTemplate.Volumes must not use these names nor ReservedVolumeNamePrefix.
+var ReservedVolumeNames = []string{"repos", "config", "auth", "tls", "ldap-bind", "ldap-ca"}
 
+// ReservedVolumeNamePrefix is a prefix of names of the volumes that dump files of SVNRepositories are mounted from.
+const ReservedVolumeNamePrefix = "source-"
+
+// ReservedEnvPrefix is a prefix of names of environment variables that the operator sets on the SVN server container.
+const ReservedEnvPrefix = "SVN_"
+
+// ContainerNameSVN is the name of the SVN server container, which sidecars must not use.
+const ContainerNameSVN = "svn"
+
+// Here is a list of condition types that all resources have.
+// They follow the conventions of Kubernetes:
+// https://github.com/kubernetes/community/blob/master/contributors/devel/sig-architecture/api-conventions.md#typical-status-properties
 const (
-	ConditionTypeNone   ConditionType = ""
-	ConditionTypeSynced ConditionType = "Synced"
-	ConditionTypeFailed ConditionType = "Failed"
+	// ConditionTypeReady means the resource has been applied to the SVN server and is ready to use.
+	ConditionTypeReady = "Ready"
+	// ConditionTypeProgressing means the resource is being applied to the SVN server.
+	ConditionTypeProgressing = "Progressing"
+	// ConditionTypeDegraded means the resource cannot be applied to the SVN server.
+	ConditionTypeDegraded = "Degraded"
+
+	// ConditionTypeArchived means the SVNRepository has been archived before deletion.
+	ConditionTypeArchived = "Archived"
+	// ConditionTypeDeleted means the SVNRepository has been deleted permanently.
+	ConditionTypeDeleted = "Deleted"
+)
+
+// Here is a list of reasons of conditions.
+const (
+	// ReasonSynced means the resource is written into the configuration of the SVNServer.
+	ReasonSynced = "Synced"
+	// ReasonSVNServerNotFound means the SVNServer that the resource refers to does not exist.
+	ReasonSVNServerNotFound = "SVNServerNotFound"
+	// ReasonInvalidGroup means the resource refers to SVNGroups that do not exist or are invalid.
+	ReasonInvalidGroup = "InvalidGroup"
+	// ReasonInvalidPassword means the password of the SVNUser is missing or cannot be read.
+	ReasonInvalidPassword = "InvalidPassword"
+
+	// ReasonLoading means the dump file of the SVNRepository is being loaded.
+	ReasonLoading = "Loading"
+	// ReasonLoadFailed means the dump file of the SVNRepository could not be loaded.
+	ReasonLoadFailed = "LoadFailed"
+
+	// ReasonRepositoryRemoved means the repository has been archived or deleted.
+	ReasonRepositoryRemoved = "RepositoryRemoved"
+	// ReasonRepositoryNotFound means the repository did not exist when it was about to be archived.
+	ReasonRepositoryNotFound = "RepositoryNotFound"
+
+	// ReasonAvailable means all pods of the SVNServer are up to date and ready.
+	ReasonAvailable = "Available"
+	// ReasonRollingOut means pods of the SVNServer are being created or updated.
+	ReasonRollingOut = "RollingOut"
+	// ReasonApplyingConfig means some pods of the SVNServer have not applied the latest configuration yet.
+	ReasonApplyingConfig = "ApplyingConfig"
+	// ReasonApplyFailed means some pods of the SVNServer failed to apply the latest configuration.
+	ReasonApplyFailed = "ApplyFailed"
+
+	// ReasonServiceFailed means the Service of the SVNServer could not be computed, fetched or created.
+	ReasonServiceFailed = "ServiceFailed"
+	// ReasonStatefulSetFailed means the StatefulSet of the SVNServer could not be computed, fetched, created or updated.
+	ReasonStatefulSetFailed = "StatefulSetFailed"
+	// ReasonIngressFailed means the Ingress or the HTTPRoute of the SVNServer could not be computed, fetched,
+	// created, updated or deleted.
+	ReasonIngressFailed = "IngressFailed"
+	// ReasonConfigMapFailed means the ConfigMap of the SVNServer could not be computed, fetched, created or updated.
+	ReasonConfigMapFailed = "ConfigMapFailed"
+	// ReasonSecretFailed means the Secret of the SVNServer or Secrets referred to by SVNUsers could not be
+	// computed, fetched, created or updated.
+	ReasonSecretFailed = "SecretFailed"
+	// ReasonRBACFailed means the Role or the RoleBinding that let the server updater watch the configuration
+	// could not be computed, created, updated or deleted.
+	ReasonRBACFailed = "RBACFailed"
+	// ReasonListFailed means SVNRepositories, SVNGroups or SVNUsers of the SVNServer could not be listed.
+	ReasonListFailed = "ListFailed"
+	// ReasonChildUpdateFailed means SVNRepositories, SVNGroups or SVNUsers of the SVNServer could not be updated.
+	ReasonChildUpdateFailed = "ChildUpdateFailed"
+	// ReasonReconcileFailed means the SVNServer could not be reconciled for other reasons.
+	ReasonReconcileFailed = "ReconcileFailed"
 )
 
 // SVNServerStatus defines the observed state of SVNServer
 type SVNServerStatus struct {
 	// +kubebuilder:validation:Optional
-	Conditions []Condition `json:"conditions"`
+	// +listType=map
+	// +listMapKey=type
+	// +patchMergeKey=type
+	// +patchStrategy=merge
+	// Conditions are the latest observations of the SVNServer, keyed by type.
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+
+	// +kubebuilder:validation:Optional
+	// ObservedGeneration is the generation of the SVNServer that the status reflects.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// URL is the external URL of the repositories, e.g. `https://svn.example.com/repos/`.
+	// It is empty if neither spec.ingress nor spec.httpRoute is specified.
+	URL string `json:"url,omitempty"`
 }
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+//+kubebuilder:printcolumn:name="Reason",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].reason`,priority=1
+//+kubebuilder:printcolumn:name="URL",type=string,JSONPath=`.status.url`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // SVNServer is the Schema for the svnservers API
 type SVNServer struct {
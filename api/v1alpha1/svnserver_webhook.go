@@ -0,0 +1,375 @@
+/*
+Copyright 2024 markzhang.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// SetupWebhookWithManager registers the mutating and validating webhooks of SVNServers.
+func (r *SVNServer) SetupWebhookWithManager(mgr ctrl.Manager, defaults SVNServerDefaults) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		WithDefaulter(&SVNServerDefaulter{Defaults: defaults}).
+		WithValidator(&SVNServerValidator{}).
+		Complete()
+}
+
+// SVNServerDefaults are operator-level defaults of SVNServers.
+// Empty fields are not defaulted.
+//
+// +kubebuilder:object:generate=false
+type SVNServerDefaults struct {
+	// Image is the default of spec.podTemplate.image.
+	Image string `json:"image,omitempty"`
+
+	// ServiceAccountName is the default of spec.podTemplate.serviceAccountName.
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+
+	// StorageSize is the default of the storage request of spec.volumeClaimTemplate (e.g. `1Gi`).
+	StorageSize string `json:"storageSize,omitempty"`
+
+	// StorageClassName is the default of spec.volumeClaimTemplate.spec.storageClassName.
+	StorageClassName string `json:"storageClassName,omitempty"`
+}
+
+// Validate returns an error if the defaults cannot be applied to SVNServers.
+func (d *SVNServerDefaults) Validate() error {
+	if d.StorageSize != "" {
+		if _, err := resource.ParseQuantity(d.StorageSize); err != nil {
+			return fmt.Errorf("invalid storage size %q: %w", d.StorageSize, err)
+		}
+	}
+	return nil
+}
+
+//+kubebuilder:webhook:path=/mutate-svn-zhangyi-chat-v1alpha1-svnserver,mutating=true,failurePolicy=fail,sideEffects=None,groups=svn.zhangyi.chat,resources=svnservers,verbs=create;update,versions=v1alpha1,name=msvnserver.kb.io,admissionReviewVersions=v1
+
+// SVNServerDefaulter writes SVNServerDefaults into the specs of SVNServers,
+// so that the values actually in use can be seen with `kubectl get svnserver -o yaml`.
+//
+// +kubebuilder:object:generate=false
+type SVNServerDefaulter struct {
+	Defaults SVNServerDefaults
+}
+
+var _ admission.CustomDefaulter = &SVNServerDefaulter{}
+
+// Default fills in the image and the service account of the pods.
+// The volume claim template is filled in only on creation because it cannot be updated afterwards.
+func (d *SVNServerDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	s, ok := obj.(*SVNServer)
+	if !ok {
+		return fmt.Errorf("expected an SVNServer but got %T", obj)
+	}
+	pt := &s.Spec.PodTemplate
+	if pt.Image == "" {
+		pt.Image = d.Defaults.Image
+	}
+	if pt.ServiceAccountName == "" {
+		pt.ServiceAccountName = d.Defaults.ServiceAccountName
+	}
+
+	req, err := admission.RequestFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	if req.Operation != admissionv1.Create {
+		return nil
+	}
+	pvc := &s.Spec.VolumeClaimTemplate.Spec
+	if len(pvc.AccessModes) == 0 {
+		pvc.AccessModes = []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce}
+	}
+	if _, ok := pvc.Resources.Requests[corev1.ResourceStorage]; !ok && d.Defaults.StorageSize != "" {
+		size, err := resource.ParseQuantity(d.Defaults.StorageSize)
+		if err != nil {
+			return err
+		}
+		if pvc.Resources.Requests == nil {
+			pvc.Resources.Requests = corev1.ResourceList{}
+		}
+		pvc.Resources.Requests[corev1.ResourceStorage] = size
+	}
+	if pvc.StorageClassName == nil && d.Defaults.StorageClassName != "" {
+		storageClassName := d.Defaults.StorageClassName
+		pvc.StorageClassName = &storageClassName
+	}
+	return nil
+}
+
+//+kubebuilder:webhook:path=/validate-svn-zhangyi-chat-v1alpha1-svnserver,mutating=false,failurePolicy=fail,sideEffects=None,groups=svn.zhangyi.chat,resources=svnservers,verbs=create;update,versions=v1alpha1,name=vsvnserver.kb.io,admissionReviewVersions=v1
+
+// SVNServerValidator validates SVNServers.
+//
+// +kubebuilder:object:generate=false
+type SVNServerValidator struct{}
+
+var _ admission.CustomValidator = &SVNServerValidator{}
+
+// ValidateCreate rejects SVNServers whose names cannot be used as names of Services, and invalid specs.
+func (v *SVNServerValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	s, ok := obj.(*SVNServer)
+	if !ok {
+		return nil, fmt.Errorf("expected an SVNServer but got %T", obj)
+	}
+	var errs field.ErrorList
+	// The name must be valid even with the suffix of the client-facing Service.
+	for _, msg := range validation.IsDNS1035Label(s.Name + ClientServiceSuffix) {
+		errs = append(errs, field.Invalid(field.NewPath("metadata", "name"), s.Name, msg))
+	}
+	errs = append(errs, validateSVNServerSpec(&s.Spec)...)
+	return nil, invalidOrNil("SVNServer", s.Name, errs)
+}
+
+// ValidateUpdate rejects invalid specs and changes to spec.volumeClaimTemplate,
+// which cannot be applied to the StatefulSet.
+func (v *SVNServerValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldServer, ok := oldObj.(*SVNServer)
+	if !ok {
+		return nil, fmt.Errorf("expected an SVNServer but got %T", oldObj)
+	}
+	s, ok := newObj.(*SVNServer)
+	if !ok {
+		return nil, fmt.Errorf("expected an SVNServer but got %T", newObj)
+	}
+	errs := validateSVNServerSpec(&s.Spec)
+	if !equality.Semantic.DeepEqual(oldServer.Spec.VolumeClaimTemplate.Spec, s.Spec.VolumeClaimTemplate.Spec) {
+		errs = append(errs, field.Forbidden(field.NewPath("spec", "volumeClaimTemplate", "spec"),
+			"field is immutable because volumeClaimTemplates of StatefulSets cannot be updated"))
+	}
+	return nil, invalidOrNil("SVNServer", s.Name, errs)
+}
+
+// ValidateDelete does nothing because deletions are not validated.
+func (v *SVNServerValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validateSVNServerSpec checks combinations of fields that the schema of the CRD cannot express.
+func validateSVNServerSpec(spec *SVNServerSpec) field.ErrorList {
+	var errs field.ErrorList
+	if svc := spec.Service; svc != nil {
+		fldPath := field.NewPath("spec", "service")
+		ports := map[int32]bool{}
+		for i, p := range svc.Ports {
+			if ports[p.Port] {
+				errs = append(errs, field.Duplicate(fldPath.Child("ports").Index(i).Child("port"), p.Port))
+			}
+			ports[p.Port] = true
+			switch {
+			case p.Name == PortNameHTTPS && spec.TLS == nil:
+				errs = append(errs, field.Forbidden(fldPath.Child("ports").Index(i).Child("name"),
+					"https may only be exposed if spec.tls is specified"))
+			case (p.Name == PortNameHTTP || p.Name == PortNameHTTPS) && !spec.ServesProtocol(ProtocolHTTP):
+				errs = append(errs, field.Forbidden(fldPath.Child("ports").Index(i).Child("name"),
+					"may only be exposed if spec.protocols contains http"))
+			case p.Name == PortNameSVN && !spec.ServesProtocol(ProtocolSVN):
+				errs = append(errs, field.Forbidden(fldPath.Child("ports").Index(i).Child("name"),
+					"svn may only be exposed if spec.protocols contains svn"))
+			}
+			if p.NodePort != 0 && (svc.Type == "" || svc.Type == string(corev1.ServiceTypeClusterIP)) {
+				errs = append(errs, field.Forbidden(fldPath.Child("ports").Index(i).Child("nodePort"),
+					"may not be specified if type is ClusterIP"))
+			}
+		}
+		if len(svc.LoadBalancerSourceRanges) > 0 && svc.Type != string(corev1.ServiceTypeLoadBalancer) {
+			errs = append(errs, field.Forbidden(fldPath.Child("loadBalancerSourceRanges"),
+				"may only be specified if type is LoadBalancer"))
+		}
+		for i, cidr := range svc.LoadBalancerSourceRanges {
+			if _, _, err := net.ParseCIDR(cidr); err != nil {
+				errs = append(errs, field.Invalid(fldPath.Child("loadBalancerSourceRanges").Index(i), cidr, "must be a CIDR"))
+			}
+		}
+	}
+
+	for i, p := range spec.Protocols {
+		if p != ProtocolHTTP && p != ProtocolSVN {
+			errs = append(errs, field.NotSupported(field.NewPath("spec", "protocols").Index(i), p, []string{ProtocolHTTP, ProtocolSVN}))
+		}
+	}
+	errs = append(errs, validateAuthentication(spec)...)
+	errs = append(errs, validatePodTemplate(&spec.PodTemplate)...)
+	if spec.ConfigSync == ConfigSyncAPI && spec.PodTemplate.ServiceAccountName == "default" {
+		// Any pod in the namespace runs as the default ServiceAccount, which would be able to read the Secret.
+		errs = append(errs, field.Forbidden(field.NewPath("spec", "podTemplate", "serviceAccountName"),
+			"may not be default if spec.configSync is API; leave it empty to use a dedicated ServiceAccount"))
+	}
+	errs = append(errs, validateApache(spec.Apache)...)
+	if !spec.ServesProtocol(ProtocolHTTP) {
+		for _, f := range []struct {
+			name      string
+			specified bool
+		}{
+			{"tls", spec.TLS != nil},
+			{"ingress", spec.Ingress != nil},
+			{"httpRoute", spec.HTTPRoute != nil},
+		} {
+			if f.specified {
+				errs = append(errs, field.Forbidden(field.NewPath("spec", f.name), "may only be specified if spec.protocols contains http"))
+			}
+		}
+	}
+	if spec.TLS != nil && spec.TLS.RedirectHTTP && (spec.Ingress != nil || spec.HTTPRoute != nil) {
+		errs = append(errs, field.Forbidden(field.NewPath("spec", "tls", "redirectHTTP"),
+			"may not be enabled together with spec.ingress or spec.httpRoute, which route to the HTTP port"))
+	}
+	if spec.Ingress != nil && spec.HTTPRoute != nil {
+		errs = append(errs, field.Forbidden(field.NewPath("spec", "httpRoute"), "may not be specified together with spec.ingress"))
+	}
+	if ing := spec.Ingress; ing != nil {
+		fldPath := field.NewPath("spec", "ingress")
+		errs = append(errs, validation.IsFullyQualifiedDomainName(fldPath.Child("host"), ing.Host)...)
+		if ing.TLS != nil && ing.TLS.SecretName == "" && ing.TLS.IssuerRef == nil {
+			errs = append(errs, field.Required(fldPath.Child("tls"), "secretName or issuerRef must be specified"))
+		}
+	}
+	if route := spec.HTTPRoute; route != nil {
+		errs = append(errs, validation.IsFullyQualifiedDomainName(field.NewPath("spec", "httpRoute", "host"), route.Host)...)
+	}
+	return errs
+}
+
+// validateApache checks the values that are rendered into the configuration of Apache as they are.
+func validateApache(apache *ApacheSpec) field.ErrorList {
+	var errs field.ErrorList
+	if apache == nil {
+		return errs
+	}
+	fldPath := field.NewPath("spec", "apache")
+	if apache.ServerName != "" {
+		for _, msg := range validation.IsDNS1123Subdomain(apache.ServerName) {
+			errs = append(errs, field.Invalid(fldPath.Child("serverName"), apache.ServerName, msg))
+		}
+	}
+	if apache.URLPrefix == "/" {
+		errs = append(errs, field.Invalid(fldPath.Child("urlPrefix"), apache.URLPrefix,
+			"must not be /, which would make Apache fail the probes of the pods"))
+	}
+	for _, q := range []struct {
+		name  string
+		value *resource.Quantity
+	}{
+		{"inMemoryCacheSize", apache.InMemoryCacheSize},
+		{"limitXMLRequestBody", apache.LimitXMLRequestBody},
+	} {
+		if q.value != nil && q.value.Sign() < 0 {
+			errs = append(errs, field.Invalid(fldPath.Child(q.name), q.value.String(), "must not be negative"))
+		}
+	}
+	return errs
+}
+
+// validatePodTemplate checks that the customizations of SVN server pods do not collide with
+// the volumes, environment variables and container that the operator manages.
+func validatePodTemplate(t *PodTemplate) field.ErrorList {
+	var errs field.ErrorList
+	fldPath := field.NewPath("spec", "podTemplate")
+	volumes := map[string]bool{}
+	for i, v := range t.Volumes {
+		volumes[v.Name] = true
+		for _, reserved := range ReservedVolumeNames {
+			if v.Name == reserved {
+				errs = append(errs, field.Invalid(fldPath.Child("volumes").Index(i).Child("name"), v.Name, "is reserved for the operator"))
+			}
+		}
+		if strings.HasPrefix(v.Name, ReservedVolumeNamePrefix) {
+			errs = append(errs, field.Invalid(fldPath.Child("volumes").Index(i).Child("name"), v.Name,
+				fmt.Sprintf("must not start with %q, which is reserved for the operator", ReservedVolumeNamePrefix)))
+		}
+	}
+	mountPaths := map[string]bool{}
+	for i, m := range t.VolumeMounts {
+		if !volumes[m.Name] {
+			errs = append(errs, field.NotFound(fldPath.Child("volumeMounts").Index(i).Child("name"), m.Name))
+		}
+		if mountPaths[m.MountPath] {
+			errs = append(errs, field.Duplicate(fldPath.Child("volumeMounts").Index(i).Child("mountPath"), m.MountPath))
+		}
+		mountPaths[m.MountPath] = true
+	}
+	for i, e := range t.Env {
+		if strings.HasPrefix(e.Name, ReservedEnvPrefix) {
+			errs = append(errs, field.Invalid(fldPath.Child("env").Index(i).Child("name"), e.Name,
+				fmt.Sprintf("must not start with %q, which is reserved for the operator", ReservedEnvPrefix)))
+		}
+	}
+	for i, c := range t.Sidecars {
+		if c.Name == ContainerNameSVN {
+			errs = append(errs, field.Invalid(fldPath.Child("sidecars").Index(i).Child("name"), c.Name, "is reserved for the SVN server container"))
+		}
+	}
+	return errs
+}
+
+// ldapAttributePattern matches names of LDAP attributes.
+var ldapAttributePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9-]*$`)
+
+// validateAuthentication checks that the LDAP directory is specified in the LDAP mode and only in it.
+func validateAuthentication(spec *SVNServerSpec) field.ErrorList {
+	var errs field.ErrorList
+	auth := spec.Authentication
+	if auth == nil {
+		return errs
+	}
+	fldPath := field.NewPath("spec", "authentication")
+	if auth.Mode != AuthenticationModeLDAP {
+		if auth.LDAP != nil {
+			errs = append(errs, field.Forbidden(fldPath.Child("ldap"), "may only be specified if mode is LDAP"))
+		}
+		return errs
+	}
+	if auth.LDAP == nil {
+		return append(errs, field.Required(fldPath.Child("ldap"), "must be specified if mode is LDAP"))
+	}
+	if spec.ServesProtocol(ProtocolSVN) {
+		errs = append(errs, field.Forbidden(field.NewPath("spec", "protocols"), "svn cannot be served if authentication mode is LDAP"))
+	}
+	u, err := url.Parse(auth.LDAP.URL)
+	switch {
+	case err != nil:
+		errs = append(errs, field.Invalid(fldPath.Child("ldap", "url"), auth.LDAP.URL, err.Error()))
+	case u.Scheme != "ldap" && u.Scheme != "ldaps", u.Host == "", u.RawQuery != "", strings.Contains(auth.LDAP.URL, "?"):
+		errs = append(errs, field.Invalid(fldPath.Child("ldap", "url"), auth.LDAP.URL,
+			"must be an ldap:// or ldaps:// URL with an optional base DN and no query"))
+	case auth.LDAP.StartTLS && u.Scheme == "ldaps":
+		errs = append(errs, field.Forbidden(fldPath.Child("ldap", "startTLS"), "may not be enabled for ldaps:// URLs"))
+	}
+	if a := auth.LDAP.UserAttribute; a != "" && !ldapAttributePattern.MatchString(a) {
+		errs = append(errs, field.Invalid(fldPath.Child("ldap", "userAttribute"), a, "must be an LDAP attribute name"))
+	}
+	if f := auth.LDAP.UserFilter; f != "" && (!strings.HasPrefix(f, "(") || !strings.HasSuffix(f, ")") || strings.ContainsAny(f, "\"\n")) {
+		errs = append(errs, field.Invalid(fldPath.Child("ldap", "userFilter"), f, "must be an LDAP filter enclosed in parentheses"))
+	}
+	return errs
+}
@@ -31,13 +31,25 @@ type SVNUserSpec struct {
 	SVNServer string `json:"svnServer,omitempty"`
 
 	// Groups is a list of SVNGroups that the user belongs to.
+	// A user that refers to unknown or invalid groups is not written into the configuration
+	// of the SVNServer and is marked as Degraded.
 	Groups []GroupRef `json:"groups,omitempty"`
 
-	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Optional
+	// Permissions are granted to the user directly, in addition to those of the groups
+	// that the user belongs to.
+	// If the user gets more than one permission to the same path, the most permissive one is used.
+	Permissions []Permission `json:"permissions,omitempty"`
+
+	// +kubebuilder:validation:Optional
 	// +kubebuilder:validation:Pattern="^[a-zA-Z0-9+/=.${}]+$"
 	// EncryptedPassword is a password encrypted by `htpasswd`.
-	// This must be computed elsewhere in order to avoid additional complexity of
-	// letting controllers manage sensitive values.
+	// At most one of EncryptedPassword and PasswordSecretRef can be specified.
+	// If neither of them is specified, the operator generates a random password and stores it
+	// into the Secret named `<name of the SVNUser>-password` that is owned by the SVNUser.
+	// The password is generated again when the SVNUser is annotated with AnnotationRegeneratePassword.
+	// Note that the hash is visible to anyone who can read the SVNUser;
+	// use PasswordSecretRef to keep it in a Secret instead.
 	//
 	// This field can be computed by the following command:
 	//   $ htpasswd -nB USERNAME | cut -d : -f 2-
@@ -47,8 +59,57 @@ type SVNUserSpec struct {
 	//
 	// See https://httpd.apache.org/docs/2.4/misc/password_encryptions.html for more information.
 	EncryptedPassword string `json:"encryptedPassword,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// PasswordSecretRef refers to a key of a Secret in the same namespace that holds the password of the user.
+	// Changes to the Secret take effect without updating the SVNUser.
+	PasswordSecretRef *PasswordSecretRef `json:"passwordSecretRef,omitempty"`
 }
 
+// PasswordSecretRef is a reference to a password in a Secret.
+type PasswordSecretRef struct {
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	// Name is the name of the Secret.
+	Name string `json:"name"`
+
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default=password
+	// Key is the key of the password in the Secret.
+	Key string `json:"key,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=Plaintext;Hash
+	// +kubebuilder:default=Plaintext
+	// Type is the format of the password.
+	// `Plaintext` means the Secret holds a raw password, which is hashed by the operator with bcrypt.
+	// `Hash` means the Secret holds a hash computed by `htpasswd` like EncryptedPassword.
+	Type string `json:"type,omitempty"`
+}
+
+// Here is a list of formats of passwords in Secrets.
+const (
+	// PasswordTypePlaintext means the Secret holds a raw password.
+	PasswordTypePlaintext = "Plaintext"
+
+	// PasswordTypeHash means the Secret holds a hash of the password.
+	PasswordTypeHash = "Hash"
+)
+
+// DefaultPasswordSecretKey is the key of passwords in Secrets used if PasswordSecretRef.Key is not specified.
+const DefaultPasswordSecretKey = "password"
+
+const (
+	// GeneratedPasswordSecretSuffix is a suffix of names of Secrets that generated passwords are stored in.
+	GeneratedPasswordSecretSuffix = "-password"
+
+	// AnnotationRegeneratePassword is an annotation to generate the password of the SVNUser again.
+	// The annotation is removed by the operator once the password is regenerated.
+	//
+	//   $ kubectl annotate svnuser USERNAME svn.zhangyi.chat/regenerate-password=true
+	AnnotationRegeneratePassword = "svn.zhangyi.chat/regenerate-password"
+)
+
 // GroupRef is a reference to SVNGroups.
 type GroupRef struct {
 	// +kubebuilder:validation:Required
@@ -60,11 +121,24 @@ type GroupRef struct {
 // SVNUserStatus defines the observed state of SVNUser
 type SVNUserStatus struct {
 	// +kubebuilder:validation:Optional
-	Conditions []Condition `json:"conditions"`
+	// +listType=map
+	// +listMapKey=type
+	// +patchMergeKey=type
+	// +patchStrategy=merge
+	// Conditions are the latest observations of the SVNUser, keyed by type.
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+
+	// +kubebuilder:validation:Optional
+	// ObservedGeneration is the generation of the SVNUser that the status reflects.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
 }
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Server",type=string,JSONPath=`.spec.svnServer`
+//+kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+//+kubebuilder:printcolumn:name="Reason",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].reason`,priority=1
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // SVNUser is the Schema for the svnusers API
 type SVNUser struct {
@@ -0,0 +1,126 @@
+/*
+Copyright 2024 markzhang.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/markzhang0928/svn-operator/pkg/svnconfig"
+)
+
+// SetupWebhookWithManager registers the validating webhook of SVNUsers.
+func (r *SVNUser) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		WithValidator(&SVNUserValidator{Client: mgr.GetAPIReader()}).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/validate-svn-zhangyi-chat-v1alpha1-svnuser,mutating=false,failurePolicy=fail,sideEffects=None,groups=svn.zhangyi.chat,resources=svnusers,verbs=create;update,versions=v1alpha1,name=vsvnuser.kb.io,admissionReviewVersions=v1
+
+// SVNUserValidator validates SVNUsers.
+//
+// +kubebuilder:object:generate=false
+type SVNUserValidator struct {
+	Client client.Reader
+}
+
+var _ admission.CustomValidator = &SVNUserValidator{}
+
+// ValidateCreate rejects SVNUsers of unknown SVNServers, SVNUsers with invalid references and invalid passwords.
+func (v *SVNUserValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	u, ok := obj.(*SVNUser)
+	if !ok {
+		return nil, fmt.Errorf("expected an SVNUser but got %T", obj)
+	}
+	fieldErr, err := validateSVNServerExists(ctx, v.Client, u.Namespace, u.Spec.SVNServer)
+	if err != nil {
+		return nil, err
+	}
+	if fieldErr != nil {
+		return nil, invalidOrNil("SVNUser", u.Name, field.ErrorList{fieldErr})
+	}
+	errs, err := v.validate(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+	return nil, invalidOrNil("SVNUser", u.Name, errs)
+}
+
+// ValidateUpdate rejects changes to spec.svnServer, SVNUsers with invalid references and invalid passwords.
+func (v *SVNUserValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldUser, ok := oldObj.(*SVNUser)
+	if !ok {
+		return nil, fmt.Errorf("expected an SVNUser but got %T", oldObj)
+	}
+	u, ok := newObj.(*SVNUser)
+	if !ok {
+		return nil, fmt.Errorf("expected an SVNUser but got %T", newObj)
+	}
+	if specUnchanged(oldUser.Spec, u.Spec) {
+		return nil, nil
+	}
+	if fieldErr := validateSVNServerUnchanged(oldUser.Spec.SVNServer, u.Spec.SVNServer); fieldErr != nil {
+		return nil, invalidOrNil("SVNUser", u.Name, field.ErrorList{fieldErr})
+	}
+	errs, err := v.validate(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+	return nil, invalidOrNil("SVNUser", u.Name, errs)
+}
+
+// ValidateDelete does nothing because deletions are not validated.
+func (v *SVNUserValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validate checks the password, the permissions and the groups of u.
+func (v *SVNUserValidator) validate(ctx context.Context, u *SVNUser) (field.ErrorList, error) {
+	var errs field.ErrorList
+	if u.Spec.EncryptedPassword != "" {
+		fldPath := field.NewPath("spec", "encryptedPassword")
+		if u.Spec.PasswordSecretRef != nil {
+			errs = append(errs, field.Forbidden(fldPath, "must not be specified together with spec.passwordSecretRef"))
+		}
+		if err := svnconfig.ValidatePasswordHash(u.Spec.EncryptedPassword); err != nil {
+			errs = append(errs, field.Invalid(fldPath, u.Spec.EncryptedPassword, err.Error()))
+		}
+	}
+
+	permErrs, err := validatePermissions(ctx, v.Client, u.Namespace, u.Spec.SVNServer, u.Spec.Permissions)
+	if err != nil {
+		return nil, err
+	}
+	errs = append(errs, permErrs...)
+
+	if len(u.Spec.Groups) > 0 {
+		groups, err := listGroups(ctx, v.Client, u.Namespace, u.Spec.SVNServer)
+		if err != nil {
+			return nil, err
+		}
+		errs = append(errs, validateGroupRefs(u.Spec.Groups, groups)...)
+	}
+	return errs, nil
+}
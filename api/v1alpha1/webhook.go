@@ -0,0 +1,140 @@
+/*
+Copyright 2024 markzhang.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/markzhang0928/svn-operator/pkg/svnconfig"
+)
+
+// The validating webhooks reject changes that the controller would otherwise only report
+// as Degraded conditions after the fact. They read other resources directly from the API server
+// rather than from the cache, so that resources applied together in one manifest can refer to
+// each other as long as they are listed in order.
+
+// invalidOrNil returns an Invalid error of the resource if errs is not empty.
+func invalidOrNil(kind, name string, errs field.ErrorList) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(GroupVersion.WithKind(kind).GroupKind(), name, errs)
+}
+
+// specUnchanged reports whether an update leaves the spec as is, e.g. when the controller
+// adds or removes finalizers. Such updates are always allowed so that resources that became
+// invalid after creation (e.g. whose groups have been deleted) can still be finalized.
+func specUnchanged(oldSpec, newSpec interface{}) bool {
+	return equality.Semantic.DeepEqual(oldSpec, newSpec)
+}
+
+// validateSVNServerUnchanged rejects updates that move a resource to another SVNServer,
+// which would leave the actual repository, group or user behind on the old server.
+func validateSVNServerUnchanged(oldServer, newServer string) *field.Error {
+	if oldServer == newServer {
+		return nil
+	}
+	return field.Invalid(field.NewPath("spec", "svnServer"), newServer, "field is immutable")
+}
+
+// validateSVNServerExists rejects references to SVNServers that do not exist.
+func validateSVNServerExists(ctx context.Context, c client.Reader, namespace, server string) (*field.Error, error) {
+	s := &SVNServer{}
+	err := c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: server}, s)
+	if apierrors.IsNotFound(err) {
+		return field.NotFound(field.NewPath("spec", "svnServer"), server), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+// validatePermissions rejects permissions to unknown repositories or to repositories of
+// other SVNServers, and permissions to the same path that are listed more than once.
+func validatePermissions(ctx context.Context, c client.Reader, namespace, server string, perms []Permission) (field.ErrorList, error) {
+	var errs field.ErrorList
+	if len(perms) == 0 {
+		return errs, nil
+	}
+	repos := &SVNRepositoryList{}
+	if err := c.List(ctx, repos, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+	serverOf := make(map[string]string, len(repos.Items))
+	for _, r := range repos.Items {
+		serverOf[r.Name] = r.Spec.SVNServer
+	}
+
+	seen := map[string]bool{}
+	for i, p := range perms {
+		fldPath := field.NewPath("spec", "permissions").Index(i)
+		s, ok := serverOf[p.Repository]
+		if !ok {
+			errs = append(errs, field.NotFound(fldPath.Child("repository"), p.Repository))
+		} else if s != server {
+			errs = append(errs, field.Invalid(fldPath.Child("repository"), p.Repository,
+				fmt.Sprintf("SVNRepository belongs to another SVNServer %q", s)))
+		}
+		key := p.Repository + ":" + svnconfig.NormalizePath(p.Path)
+		if seen[key] {
+			errs = append(errs, field.Duplicate(fldPath, key))
+		}
+		seen[key] = true
+	}
+	return errs, nil
+}
+
+// listGroups returns SVNGroups of the SVNServer keyed by their names.
+func listGroups(ctx context.Context, c client.Reader, namespace, server string) (map[string]*SVNGroup, error) {
+	list := &SVNGroupList{}
+	if err := c.List(ctx, list, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+	groups := make(map[string]*SVNGroup, len(list.Items))
+	for i := range list.Items {
+		g := &list.Items[i]
+		if g.Spec.SVNServer == server {
+			groups[g.Name] = g
+		}
+	}
+	return groups, nil
+}
+
+// validateGroupRefs rejects references to SVNGroups that are not found in groups
+// and references that are listed more than once.
+func validateGroupRefs(refs []GroupRef, groups map[string]*SVNGroup) field.ErrorList {
+	var errs field.ErrorList
+	seen := map[string]bool{}
+	for i, ref := range refs {
+		fldPath := field.NewPath("spec", "groups").Index(i).Child("name")
+		if _, ok := groups[ref.Name]; !ok {
+			errs = append(errs, field.NotFound(fldPath, ref.Name))
+		}
+		if seen[ref.Name] {
+			errs = append(errs, field.Duplicate(fldPath, ref.Name))
+		}
+		seen[ref.Name] = true
+	}
+	return errs
+}
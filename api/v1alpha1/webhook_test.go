@@ -0,0 +1,422 @@
+/*
+Copyright 2024 markzhang.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gstruct"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+var _ = Describe("Validating webhooks", func() {
+	const namespace = "default"
+	ctx := context.Background()
+	var c client.Client
+
+	meta := func(name string) metav1.ObjectMeta {
+		return metav1.ObjectMeta{Namespace: namespace, Name: name}
+	}
+	repo := func(name, server string) *SVNRepository {
+		return &SVNRepository{ObjectMeta: meta(name), Spec: SVNRepositorySpec{SVNServer: server}}
+	}
+	group := func(name string, members ...string) *SVNGroup {
+		g := &SVNGroup{ObjectMeta: meta(name), Spec: SVNGroupSpec{SVNServer: "server"}}
+		for _, m := range members {
+			g.Spec.Groups = append(g.Spec.Groups, GroupRef{Name: m})
+		}
+		return g
+	}
+	expectInvalid := func(_ admission.Warnings, err error) {
+		ExpectWithOffset(1, apierrors.IsInvalid(err)).To(BeTrue(), "expected an Invalid error but got %v", err)
+	}
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(AddToScheme(scheme)).To(Succeed())
+		c = fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+			&SVNServer{ObjectMeta: meta("server")},
+			&SVNServer{ObjectMeta: meta("another")},
+			repo("hoge", "server"),
+			repo("fuga", "another"),
+			group("readers"),
+			group("writers", "readers"),
+		).Build()
+	})
+
+	Describe("SVNServer", func() {
+		v := &SVNServerValidator{}
+		d := &SVNServerDefaulter{Defaults: SVNServerDefaults{
+			Image:              "svn-server:latest",
+			ServiceAccountName: "svn",
+			StorageSize:        "1Gi",
+			StorageClassName:   "standard",
+		}}
+		withOperation := func(op admissionv1.Operation) context.Context {
+			return admission.NewContextWithRequest(ctx, admission.Request{
+				AdmissionRequest: admissionv1.AdmissionRequest{Operation: op},
+			})
+		}
+
+		It("rejects names that cannot be used for Services", func() {
+			expectInvalid(v.ValidateCreate(ctx, &SVNServer{ObjectMeta: meta("svn.example.com")}))
+			Expect(v.ValidateCreate(ctx, &SVNServer{ObjectMeta: meta("svn")})).Error().NotTo(HaveOccurred())
+		})
+
+		It("rejects names that are too long for the client-facing Service", func() {
+			long := strings.Repeat("a", 60)
+			expectInvalid(v.ValidateCreate(ctx, &SVNServer{ObjectMeta: meta(long)}))
+		})
+
+		It("rejects invalid Service configurations", func() {
+			s := &SVNServer{ObjectMeta: meta("svn")}
+			s.Spec.Service = &ServiceSpec{
+				Type:                     "LoadBalancer",
+				Ports:                    []ServicePort{{Name: PortNameHTTP, Port: 8080, NodePort: 30080}},
+				LoadBalancerSourceRanges: []string{"10.0.0.0/8"},
+			}
+			Expect(v.ValidateCreate(ctx, s)).Error().NotTo(HaveOccurred())
+
+			s.Spec.Service.LoadBalancerSourceRanges = []string{"10.0.0.0"}
+			expectInvalid(v.ValidateCreate(ctx, s))
+
+			s.Spec.Service.LoadBalancerSourceRanges = nil
+			s.Spec.Service.Type = "ClusterIP"
+			expectInvalid(v.ValidateCreate(ctx, s))
+		})
+
+		It("rejects HTTPS configurations without TLS", func() {
+			s := &SVNServer{ObjectMeta: meta("svn")}
+			s.Spec.Service = &ServiceSpec{Ports: []ServicePort{{Name: PortNameHTTPS, Port: 443}}}
+			expectInvalid(v.ValidateCreate(ctx, s))
+
+			s.Spec.TLS = &TLSSpec{SecretName: "svn-tls", RedirectHTTP: true}
+			Expect(v.ValidateCreate(ctx, s)).Error().NotTo(HaveOccurred())
+
+			s.Spec.Ingress = &IngressSpec{Host: "svn.example.com"}
+			expectInvalid(v.ValidateCreate(ctx, s))
+		})
+
+		It("rejects configurations of protocols that are not served", func() {
+			s := &SVNServer{ObjectMeta: meta("svn")}
+			s.Spec.Service = &ServiceSpec{Ports: []ServicePort{{Name: PortNameSVN, Port: 3690}}}
+			expectInvalid(v.ValidateCreate(ctx, s))
+
+			s.Spec.Protocols = []string{"ftp"}
+			expectInvalid(v.ValidateCreate(ctx, s))
+
+			s.Spec.Protocols = []string{ProtocolSVN}
+			Expect(v.ValidateCreate(ctx, s)).Error().NotTo(HaveOccurred())
+
+			s.Spec.Ingress = &IngressSpec{Host: "svn.example.com"}
+			expectInvalid(v.ValidateCreate(ctx, s))
+
+			s.Spec.Ingress = nil
+			s.Spec.Service.Ports = append(s.Spec.Service.Ports, ServicePort{Name: PortNameHTTP, Port: 80})
+			expectInvalid(v.ValidateCreate(ctx, s))
+		})
+
+		It("rejects invalid LDAP configurations", func() {
+			s := &SVNServer{ObjectMeta: meta("svn")}
+			s.Spec.Authentication = &AuthenticationSpec{Mode: AuthenticationModeLDAP}
+			expectInvalid(v.ValidateCreate(ctx, s))
+
+			s.Spec.Authentication.LDAP = &LDAPSpec{
+				URL:            "ldap://openldap:389/ou=people,dc=example,dc=org",
+				UserFilter:     "(objectClass=inetOrgPerson)",
+				StartTLS:       true,
+				BindSecretName: "ldap-bind",
+			}
+			Expect(v.ValidateCreate(ctx, s)).Error().NotTo(HaveOccurred())
+
+			for _, u := range []string{"https://openldap", "ldap://openldap/dc=example,dc=org?uid", "ldaps://openldap"} {
+				ldap := *s.Spec.Authentication.LDAP
+				ldap.URL = u
+				invalid := s.DeepCopy()
+				invalid.Spec.Authentication.LDAP = &ldap
+				expectInvalid(v.ValidateCreate(ctx, invalid))
+			}
+
+			invalid := s.DeepCopy()
+			invalid.Spec.Authentication.LDAP.UserFilter = "objectClass=*"
+			expectInvalid(v.ValidateCreate(ctx, invalid))
+
+			invalid = s.DeepCopy()
+			invalid.Spec.Protocols = []string{ProtocolHTTP, ProtocolSVN}
+			expectInvalid(v.ValidateCreate(ctx, invalid))
+
+			invalid = s.DeepCopy()
+			invalid.Spec.Authentication.Mode = AuthenticationModeFile
+			expectInvalid(v.ValidateCreate(ctx, invalid))
+		})
+
+		It("rejects invalid Apache configurations", func() {
+			s := &SVNServer{ObjectMeta: meta("svn")}
+			s.Spec.Apache = &ApacheSpec{
+				ServerName:          "svn.example.com",
+				LimitXMLRequestBody: ptr.To(resource.MustParse("0")),
+			}
+			Expect(v.ValidateCreate(ctx, s)).Error().NotTo(HaveOccurred())
+
+			invalid := s.DeepCopy()
+			invalid.Spec.Apache.ServerName = "svn example"
+			expectInvalid(v.ValidateCreate(ctx, invalid))
+
+			invalid = s.DeepCopy()
+			invalid.Spec.Apache.InMemoryCacheSize = ptr.To(resource.MustParse("-1Mi"))
+			expectInvalid(v.ValidateCreate(ctx, invalid))
+
+			By("serving the repositories at the root that the pods are probed at")
+			invalid = s.DeepCopy()
+			invalid.Spec.Apache.URLPrefix = "/"
+			expectInvalid(v.ValidateCreate(ctx, invalid))
+		})
+
+		It("rejects the default ServiceAccount if the configuration is synced through the API", func() {
+			s := &SVNServer{ObjectMeta: meta("svn")}
+			s.Spec.ConfigSync = ConfigSyncAPI
+			Expect(v.ValidateCreate(ctx, s)).Error().NotTo(HaveOccurred())
+
+			invalid := s.DeepCopy()
+			invalid.Spec.PodTemplate.ServiceAccountName = "default"
+			expectInvalid(v.ValidateCreate(ctx, invalid))
+		})
+
+		It("rejects pod customizations that collide with the operator", func() {
+			s := &SVNServer{ObjectMeta: meta("svn")}
+			s.Spec.PodTemplate = PodTemplate{
+				Env:          []corev1.EnvVar{{Name: "TZ", Value: "Asia/Tokyo"}},
+				Volumes:      []corev1.Volume{{Name: "logs", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}}},
+				VolumeMounts: []corev1.VolumeMount{{Name: "logs", MountPath: "/var/log/apache2"}},
+				Sidecars:     []corev1.Container{{Name: "log-shipper", Image: "fluent-bit"}},
+			}
+			Expect(v.ValidateCreate(ctx, s)).Error().NotTo(HaveOccurred())
+
+			invalid := s.DeepCopy()
+			invalid.Spec.PodTemplate.Env[0].Name = "SVN_PROTOCOLS"
+			expectInvalid(v.ValidateCreate(ctx, invalid))
+
+			for _, name := range []string{"repos", "config", "source-0123456789abcdef"} {
+				invalid = s.DeepCopy()
+				invalid.Spec.PodTemplate.Volumes[0].Name = name
+				invalid.Spec.PodTemplate.VolumeMounts[0].Name = name
+				expectInvalid(v.ValidateCreate(ctx, invalid))
+			}
+
+			invalid = s.DeepCopy()
+			invalid.Spec.PodTemplate.VolumeMounts[0].Name = "data"
+			expectInvalid(v.ValidateCreate(ctx, invalid))
+
+			invalid = s.DeepCopy()
+			invalid.Spec.PodTemplate.VolumeMounts = append(invalid.Spec.PodTemplate.VolumeMounts, invalid.Spec.PodTemplate.VolumeMounts[0])
+			expectInvalid(v.ValidateCreate(ctx, invalid))
+
+			invalid = s.DeepCopy()
+			invalid.Spec.PodTemplate.Sidecars[0].Name = ContainerNameSVN
+			expectInvalid(v.ValidateCreate(ctx, invalid))
+		})
+
+		It("rejects invalid Ingress and HTTPRoute configurations", func() {
+			s := &SVNServer{ObjectMeta: meta("svn")}
+			s.Spec.Ingress = &IngressSpec{
+				Host: "svn.example.com",
+				TLS:  &IngressTLS{IssuerRef: &IssuerRef{Kind: "ClusterIssuer", Name: "letsencrypt"}},
+			}
+			Expect(v.ValidateCreate(ctx, s)).Error().NotTo(HaveOccurred())
+
+			s.Spec.Ingress.TLS = &IngressTLS{}
+			expectInvalid(v.ValidateCreate(ctx, s))
+
+			s.Spec.Ingress.TLS = nil
+			s.Spec.Ingress.Host = "svn_example"
+			expectInvalid(v.ValidateCreate(ctx, s))
+
+			s.Spec.Ingress = nil
+			s.Spec.HTTPRoute = &HTTPRouteSpec{Host: "svn.example.com", ParentRefs: []GatewayRef{{Name: "gateway"}}}
+			Expect(v.ValidateCreate(ctx, s)).Error().NotTo(HaveOccurred())
+
+			s.Spec.Ingress = &IngressSpec{Host: "svn.example.com"}
+			expectInvalid(v.ValidateCreate(ctx, s))
+		})
+
+		It("makes spec.volumeClaimTemplate immutable", func() {
+			old := &SVNServer{ObjectMeta: meta("svn")}
+			s := old.DeepCopy()
+			s.Spec.VolumeClaimTemplate.Spec.AccessModes = []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce}
+			expectInvalid(v.ValidateUpdate(ctx, old, s))
+		})
+
+		It("writes the defaults into new SVNServers", func() {
+			s := &SVNServer{ObjectMeta: meta("svn")}
+			Expect(d.Default(withOperation(admissionv1.Create), s)).To(Succeed())
+			Expect(s.Spec.PodTemplate.Image).To(Equal("svn-server:latest"))
+			Expect(s.Spec.PodTemplate.ServiceAccountName).To(Equal("svn"))
+			pvc := s.Spec.VolumeClaimTemplate.Spec
+			Expect(pvc.AccessModes).To(ConsistOf(corev1.ReadWriteOnce))
+			Expect(pvc.Resources.Requests.Storage().String()).To(Equal("1Gi"))
+			Expect(pvc.StorageClassName).To(PointTo(Equal("standard")))
+		})
+
+		It("keeps the values specified explicitly", func() {
+			s := &SVNServer{ObjectMeta: meta("svn")}
+			s.Spec.PodTemplate.Image = "custom:latest"
+			s.Spec.VolumeClaimTemplate.Spec.Resources.Requests = corev1.ResourceList{
+				corev1.ResourceStorage: resource.MustParse("512M"),
+			}
+			Expect(d.Default(withOperation(admissionv1.Create), s)).To(Succeed())
+			Expect(s.Spec.PodTemplate.Image).To(Equal("custom:latest"))
+			Expect(s.Spec.VolumeClaimTemplate.Spec.Resources.Requests.Storage().String()).To(Equal("512M"))
+		})
+
+		It("does not change the volume claim template of existing SVNServers", func() {
+			s := &SVNServer{ObjectMeta: meta("svn")}
+			Expect(d.Default(withOperation(admissionv1.Update), s)).To(Succeed())
+			Expect(s.Spec.PodTemplate.Image).To(Equal("svn-server:latest"))
+			Expect(s.Spec.VolumeClaimTemplate.Spec).To(Equal(corev1.PersistentVolumeClaimSpec{}))
+		})
+	})
+
+	Describe("SVNRepository", func() {
+		var v *SVNRepositoryValidator
+		BeforeEach(func() { v = &SVNRepositoryValidator{Client: c} })
+
+		It("rejects unknown SVNServers", func() {
+			Expect(v.ValidateCreate(ctx, repo("piyo", "server"))).Error().NotTo(HaveOccurred())
+			expectInvalid(v.ValidateCreate(ctx, repo("piyo", "unknown")))
+		})
+
+		It("rejects invalid initial layouts", func() {
+			r := repo("piyo", "server")
+			r.Spec.InitialLayout = &InitialLayout{Directories: []string{"trunk", "../tags"}}
+			expectInvalid(v.ValidateCreate(ctx, r))
+		})
+
+		It("rejects invalid HTTP settings", func() {
+			r := repo("piyo", "server")
+			r.Spec.HTTP = &RepositoryHTTPSpec{
+				LimitRequestBody:    ptr.To(resource.MustParse("100Mi")),
+				AllowedSourceRanges: []string{"10.0.0.0/8", "192.168.1.10", "fd00::/8"},
+			}
+			Expect(v.ValidateCreate(ctx, r)).Error().NotTo(HaveOccurred())
+
+			invalid := r.DeepCopy()
+			invalid.Spec.HTTP.LimitRequestBody = ptr.To(resource.MustParse("4Gi"))
+			expectInvalid(v.ValidateCreate(ctx, invalid))
+
+			invalid = r.DeepCopy()
+			invalid.Spec.HTTP.AllowedSourceRanges = []string{"10.0.0.0/33"}
+			expectInvalid(v.ValidateUpdate(ctx, r, invalid))
+		})
+
+		It("makes spec.svnServer immutable", func() {
+			expectInvalid(v.ValidateUpdate(ctx, repo("hoge", "server"), repo("hoge", "another")))
+		})
+
+		It("allows updates that do not change the spec", func() {
+			old := repo("hoge", "server")
+			old.Spec.InitialLayout = &InitialLayout{Directories: []string{"../tags"}}
+			r := old.DeepCopy()
+			r.Finalizers = []string{"svn.zhangyi.chat/repository"}
+			Expect(v.ValidateUpdate(ctx, old, r)).Error().NotTo(HaveOccurred())
+		})
+	})
+
+	Describe("SVNGroup", func() {
+		var v *SVNGroupValidator
+		BeforeEach(func() { v = &SVNGroupValidator{Client: c} })
+
+		It("accepts valid groups", func() {
+			g := group("all", "readers", "writers")
+			g.Spec.Permissions = []Permission{{Repository: "hoge", Path: "/trunk", Permission: PermissionRW}}
+			Expect(v.ValidateCreate(ctx, g)).Error().NotTo(HaveOccurred())
+		})
+
+		It("rejects unknown groups", func() {
+			expectInvalid(v.ValidateCreate(ctx, group("all", "unknown")))
+		})
+
+		It("rejects repositories of other SVNServers", func() {
+			g := group("all")
+			g.Spec.Permissions = []Permission{{Repository: "fuga", Permission: PermissionR}}
+			expectInvalid(v.ValidateCreate(ctx, g))
+		})
+
+		It("rejects duplicate permissions", func() {
+			g := group("all")
+			g.Spec.Permissions = []Permission{
+				{Repository: "hoge", Path: "/trunk", Permission: PermissionR},
+				{Repository: "hoge", Path: "/trunk/", Permission: PermissionRW},
+			}
+			expectInvalid(v.ValidateCreate(ctx, g))
+		})
+
+		It("rejects cycles", func() {
+			expectInvalid(v.ValidateCreate(ctx, group("self", "self")))
+			expectInvalid(v.ValidateUpdate(ctx, group("readers"), group("readers", "writers")))
+		})
+	})
+
+	Describe("SVNUser", func() {
+		var v *SVNUserValidator
+		BeforeEach(func() { v = &SVNUserValidator{Client: c} })
+
+		user := func(hash string, groups ...string) *SVNUser {
+			u := &SVNUser{ObjectMeta: meta("alice"), Spec: SVNUserSpec{SVNServer: "server", EncryptedPassword: hash}}
+			for _, g := range groups {
+				u.Spec.Groups = append(u.Spec.Groups, GroupRef{Name: g})
+			}
+			return u
+		}
+		const hash = "$2y$05$Z9loUIkf0DynjbD0UMEpneKCSKYfkTCaE/pwY8wt7MtKQILxKRwjG"
+
+		It("accepts valid users", func() {
+			Expect(v.ValidateCreate(ctx, user(hash, "readers"))).Error().NotTo(HaveOccurred())
+		})
+
+		It("rejects unknown groups", func() {
+			expectInvalid(v.ValidateCreate(ctx, user(hash, "unknown")))
+		})
+
+		It("rejects malformed hashes", func() {
+			expectInvalid(v.ValidateCreate(ctx, user("himitsu")))
+		})
+
+		It("rejects both encryptedPassword and passwordSecretRef", func() {
+			u := user(hash)
+			u.Spec.PasswordSecretRef = &PasswordSecretRef{Name: "alice-password"}
+			expectInvalid(v.ValidateCreate(ctx, u))
+		})
+
+		It("makes spec.svnServer immutable", func() {
+			u := user(hash)
+			u.Spec.SVNServer = "another"
+			expectInvalid(v.ValidateUpdate(ctx, user(hash), u))
+		})
+	})
+})
@@ -20,6 +20,7 @@ import (
 	"context"
 	"crypto/tls"
 	"flag"
+	"fmt"
 	"os"
 
 	"github.com/markzhang0928/svn-operator/controllers"
@@ -27,14 +28,18 @@ import (
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+	"sigs.k8s.io/yaml"
 
 	svnv1alpha1 "github.com/markzhang0928/svn-operator/api/v1alpha1"
 	//+kubebuilder:scaffold:imports
@@ -45,10 +50,41 @@ var (
 	setupLog = ctrl.Log.WithName("setup")
 )
 
+// Config is the content of the configuration file specified by --config.
+type Config struct {
+	// SVNServerDefaults are written into SVNServers that do not specify them.
+	SVNServerDefaults svnv1alpha1.SVNServerDefaults `json:"svnServerDefaults,omitempty"`
+}
+
+// defaultConfig is used for fields that are specified neither in the configuration file nor by flags.
+var defaultConfig = Config{
+	SVNServerDefaults: svnv1alpha1.SVNServerDefaults{
+		Image:       "zhangyi330700/svn-server:v1.0.0",
+		StorageSize: "1Gi",
+	},
+}
+
+// loadConfig reads the configuration file at path on top of defaultConfig.
+func loadConfig(path string) (*Config, error) {
+	config := defaultConfig
+	if path == "" {
+		return &config, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.UnmarshalStrict(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return &config, nil
+}
+
 func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 
 	utilruntime.Must(svnv1alpha1.AddToScheme(scheme))
+	utilruntime.Must(gatewayv1.AddToScheme(scheme))
 	//+kubebuilder:scaffold:scheme
 }
 
@@ -58,6 +94,8 @@ func main() {
 	var probeAddr string
 	var secureMetrics bool
 	var enableHTTP2 bool
+	var configFile string
+	var defaults svnv1alpha1.SVNServerDefaults
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
@@ -67,6 +105,16 @@ func main() {
 		"If set the metrics endpoint is served securely")
 	flag.BoolVar(&enableHTTP2, "enable-http2", false,
 		"If set, HTTP/2 will be enabled for the metrics and webhook servers")
+	flag.StringVar(&configFile, "config", "",
+		"The path to the configuration file. Flags take precedence over the values in the file.")
+	flag.StringVar(&defaults.Image, "svnserver-image", defaultConfig.SVNServerDefaults.Image,
+		"The default container image of SVNServers.")
+	flag.StringVar(&defaults.ServiceAccountName, "svnserver-service-account", defaultConfig.SVNServerDefaults.ServiceAccountName,
+		"The default ServiceAccount of SVNServers. If empty, the default ServiceAccount of the namespace is used.")
+	flag.StringVar(&defaults.StorageSize, "svnserver-storage-size", defaultConfig.SVNServerDefaults.StorageSize,
+		"The default size of volumes of SVNServers.")
+	flag.StringVar(&defaults.StorageClassName, "svnserver-storage-class", defaultConfig.SVNServerDefaults.StorageClassName,
+		"The default StorageClass of volumes of SVNServers. If empty, the default StorageClass of the cluster is used.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -75,6 +123,28 @@ func main() {
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
+	config, err := loadConfig(configFile)
+	if err != nil {
+		setupLog.Error(err, "unable to load config", "path", configFile)
+		os.Exit(1)
+	}
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "svnserver-image":
+			config.SVNServerDefaults.Image = defaults.Image
+		case "svnserver-service-account":
+			config.SVNServerDefaults.ServiceAccountName = defaults.ServiceAccountName
+		case "svnserver-storage-size":
+			config.SVNServerDefaults.StorageSize = defaults.StorageSize
+		case "svnserver-storage-class":
+			config.SVNServerDefaults.StorageClassName = defaults.StorageClassName
+		}
+	})
+	if err := config.SVNServerDefaults.Validate(); err != nil {
+		setupLog.Error(err, "invalid SVNServer defaults")
+		os.Exit(1)
+	}
+
 	// if the enable-http2 flag is false (the default), http/2 should be disabled
 	// due to its vulnerabilities. More specifically, disabling http/2 will
 	// prevent from being vulnerable to the HTTP/2 Stream Cancelation and
@@ -106,6 +176,11 @@ func main() {
 		HealthProbeBindAddress: probeAddr,
 		LeaderElection:         enableLeaderElection,
 		LeaderElectionID:       "b1d8d6db.zhangyi.chat",
+		// Secrets are read from the API server whenever they are needed instead of being cached,
+		// since the controller watches only their metadata.
+		Client: client.Options{
+			Cache: &client.CacheOptions{DisableFor: []client.Object{&corev1.Secret{}}},
+		},
 		// LeaderElectionReleaseOnCancel defines if the leader should step down voluntarily
 		// when the Manager ends. This requires the binary to immediately end when the
 		// Manager is stopped, otherwise, this setting is unsafe. Setting this significantly
@@ -125,12 +200,33 @@ func main() {
 
 	ctx := context.Background()
 	if err = (&controllers.SVNServerReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:   mgr.GetClient(),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("svnserver-controller"),
+
+		DefaultSVNServerImage: config.SVNServerDefaults.Image,
 	}).SetupWithManager(ctx, mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "SVNServer")
 		os.Exit(1)
 	}
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+		if err = (&svnv1alpha1.SVNServer{}).SetupWebhookWithManager(mgr, config.SVNServerDefaults); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "SVNServer")
+			os.Exit(1)
+		}
+		if err = (&svnv1alpha1.SVNRepository{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "SVNRepository")
+			os.Exit(1)
+		}
+		if err = (&svnv1alpha1.SVNGroup{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "SVNGroup")
+			os.Exit(1)
+		}
+		if err = (&svnv1alpha1.SVNUser{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "SVNUser")
+			os.Exit(1)
+		}
+	}
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
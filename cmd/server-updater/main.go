@@ -17,27 +17,48 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"slices"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/fsnotify/fsnotify"
+	"github.com/go-logr/logr"
 	"github.com/go-logr/zapr"
 	"go.uber.org/zap"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
 
+	svnv1alpha1 "github.com/markzhang0928/svn-operator/api/v1alpha1"
 	"github.com/markzhang0928/svn-operator/controllers"
 	"github.com/markzhang0928/svn-operator/pkg/serverupdater"
 )
 
+// retryInterval is an interval to apply the configuration again after it fails.
+const retryInterval = 10 * time.Second
+
 func main() {
-	var initdScript, svnAdmin string
+	var initdScript, apachectl, pidFile, svnAdmin, svn, statusAddr, namespace, name, protocols string
 	var timeoutMs int
+	var watchAPI bool
 	flag.StringVar(&initdScript, "initd-script", "/etc/init.d/apache2", "Path to /etc/init.d/apache2 (or its variant)")
+	flag.StringVar(&apachectl, "apachectl", "/usr/sbin/apachectl", "Path to `apachectl` command, which validates Apache configurations before reloads")
+	flag.StringVar(&pidFile, "apache-pid-file", "/var/run/apache2/apache2.pid", "Path to the PID file of Apache, which is not reloaded until it starts")
+	flag.StringVar(&protocols, "protocols", svnv1alpha1.ProtocolHTTP, "Comma-separated list of protocols that the server serves; Apache is left alone unless it contains http")
 	flag.StringVar(&svnAdmin, "svnadmin", "/usr/bin/svnadmin", "Path to `svnadmin` command")
+	flag.StringVar(&svn, "svn", "/usr/bin/svn", "Path to `svn` command")
 	flag.IntVar(&timeoutMs, "exec-timeout", 10000, "Timeout to run commands")
+	flag.StringVar(&statusAddr, "status-bind-address", fmt.Sprintf(":%d", controllers.ServerUpdaterStatusPort), "The address the status endpoint binds to")
+	flag.BoolVar(&watchAPI, "watch-api", false, "Watch the ConfigMap and the Secret through the Kubernetes API instead of their volumes")
+	flag.StringVar(&namespace, "namespace", "", "The namespace of the ConfigMap and the Secret to watch with --watch-api")
+	flag.StringVar(&name, "name", "", "The name of the ConfigMap and the Secret to watch with --watch-api")
 	flag.Parse()
 
 	zapLog, err := zap.NewProduction()
@@ -47,54 +68,128 @@ func main() {
 	}
 	log := zapr.NewLogger(zapLog)
 
+	if !slices.Contains(strings.Split(protocols, ","), svnv1alpha1.ProtocolHTTP) {
+		initdScript, apachectl = "", ""
+	}
+
 	u := &serverupdater.Updater{
 		InitdScript: initdScript,
+		Apachectl:   apachectl,
+		PIDFile:     pidFile,
 		SvnAdmin:    svnAdmin,
+		Svn:         svn,
 		ReposConfig: filepath.Join(controllers.VolumePathConfig, controllers.ConfigMapKeyRepos),
+		ConfigDir:   controllers.VolumePathConfig,
+		AuthDir:     controllers.VolumePathAuth,
 		ReposDir:    filepath.Join(controllers.VolumePathRepos, "repos"),
+		ArchiveDir:  filepath.Join(controllers.VolumePathRepos, "archive"),
+		WorkDir:     filepath.Join(controllers.VolumePathRepos, "work"),
 		TimeoutMs:   timeoutMs,
 		Log:         log,
 	}
 
+	mux := http.NewServeMux()
+	mux.Handle(serverupdater.StatusPath, u)
+	go func() {
+		if err := http.ListenAndServe(statusAddr, mux); err != nil {
+			log.Error(err, "failed to serve status")
+			os.Exit(1)
+		}
+	}()
+
+	if watchAPI {
+		runConfigWatcher(u, namespace, name, log)
+		return
+	}
+
 	watcher, err := fsnotify.NewWatcher()
 	if err != nil {
 		log.Error(err, "failed to initialize watcher")
 		os.Exit(1)
 	}
-	if err := watcher.Add(controllers.VolumePathConfig); err != nil {
-		log.Error(err, "failed to watch config files")
-		os.Exit(1)
+	// The auth directory is watched too, since the applied configuration is reported with the hash of both.
+	for _, dir := range []string{controllers.VolumePathConfig, controllers.VolumePathAuth} {
+		if err := watcher.Add(dir); err != nil {
+			log.Error(err, "failed to watch config files", "directory", dir)
+			os.Exit(1)
+		}
 	}
 	// Volumes made from ConfigMaps stores all values inside ConfigMaps in `..data` directory.
 	// See https://github.com/kubernetes/kubernetes/blob/master/pkg/volume/util/atomic_writer.go
-	dataDir := filepath.Join(controllers.VolumePathConfig, "..data")
+	dataDirs := map[string]bool{
+		filepath.Join(controllers.VolumePathConfig, "..data"): true,
+		filepath.Join(controllers.VolumePathAuth, "..data"):   true,
+	}
 
 	signals := make(chan os.Signal, 2)
 	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
 
+	// Failures are retried even if the files do not change, since some of them go away by themselves.
+	var retry <-chan time.Time
+
 	log.Info("initializing")
 	err = u.OnConfigChanged()
 	if err != nil {
 		log.Error(err, "failed to initialize settings")
+		retry = time.After(retryInterval)
 	}
-
 	for {
+		err = nil
 		select {
 		case ev := <-watcher.Events:
 			if ev.Op&(fsnotify.Create|fsnotify.Write) == 0 {
 				continue
 			}
-			if ev.Name != dataDir {
+			if !dataDirs[ev.Name] {
 				continue
 			}
 			log.Info("detected config change", "filename", ev.Name)
 			err = u.OnConfigChanged()
-			if err != nil {
-				log.Error(err, "failed to update repository settings")
-			}
+		case <-retry:
+			log.Info("retrying to apply configuration")
+			err = u.OnConfigChanged()
 		case sig := <-signals:
 			log.Info("caught signal; quitting", "signal", sig.String())
-			break
+			return
 		}
+		retry = nil
+		if err != nil {
+			log.Error(err, "failed to update repository settings")
+			retry = time.After(retryInterval)
+		}
+	}
+}
+
+// runConfigWatcher writes the ConfigMap and the Secret into the config and auth directories
+// as soon as they change in the API, and applies them.
+func runConfigWatcher(u *serverupdater.Updater, namespace, name string, log logr.Logger) {
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		log.Error(err, "failed to load in-cluster config")
+		os.Exit(1)
+	}
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		log.Error(err, "failed to create client")
+		os.Exit(1)
+	}
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	w := &serverupdater.ConfigWatcher{
+		Client:        clientset,
+		Namespace:     namespace,
+		Name:          name,
+		ConfigDir:     controllers.VolumePathConfig,
+		AuthDir:       controllers.VolumePathAuth,
+		OnChanged:     u.OnConfigChanged,
+		RetryInterval: retryInterval,
+		Log:           log,
+	}
+	log.Info("watching config through the API", "namespace", namespace, "name", name)
+	if err := w.Run(ctx); err != nil {
+		log.Error(err, "failed to watch config")
+		os.Exit(1)
 	}
+	log.Info("quitting")
 }
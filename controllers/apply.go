@@ -0,0 +1,102 @@
+/*
+Copyright 2024 markzhang.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/util/csaupgrade"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+
+	svnv1alpha1 "github.com/markzhang0928/svn-operator/api/v1alpha1"
+)
+
+// FieldManager is the name of the field manager that the operator applies objects with.
+const FieldManager = "svn-operator"
+
+// legacyFieldManagers are field managers that older versions of the operator updated objects with.
+// They did not specify any field manager, so the API server named it after the binary.
+var legacyFieldManagers = sets.New("manager")
+
+// apply makes obj up to date by server-side apply, and returns whether the object has been created or changed.
+// obj must be built from scratch and contain all fields that the operator manages; fields that were
+// applied before but are missing in obj are removed, and fields changed by others are restored.
+// current is filled with the object before the change, which is empty if it did not exist.
+func (r *SVNServerReconciler) apply(ctx context.Context, obj, current client.Object) (created, changed bool, err error) {
+	gvk, err := apiutil.GVKForObject(obj, r.Scheme)
+	if err != nil {
+		return false, false, err
+	}
+	err = r.Get(ctx, client.ObjectKeyFromObject(obj), current)
+	if err != nil && !errors.IsNotFound(err) {
+		return false, false, err
+	}
+	found := err == nil
+	if found {
+		// Take over fields updated by older versions, so that they are removed once they are no longer applied.
+		patch, err := csaupgrade.UpgradeManagedFieldsPatch(current, legacyFieldManagers, FieldManager)
+		if err != nil {
+			return false, false, err
+		}
+		if patch != nil {
+			if err := r.Patch(ctx, current, client.RawPatch(types.JSONPatchType, patch)); err != nil {
+				return false, false, err
+			}
+		}
+	}
+
+	obj.GetObjectKind().SetGroupVersionKind(gvk)
+	if err := r.Patch(ctx, obj, client.Apply, client.FieldOwner(FieldManager), client.ForceOwnership); err != nil {
+		return !found, false, err
+	}
+	return !found, !found || obj.GetResourceVersion() != current.GetResourceVersion(), nil
+}
+
+// applyOwned applies an object owned by the SVNServer, and records events about the change.
+func (r *SVNServerReconciler) applyOwned(ctx context.Context, log logr.Logger, s *svnv1alpha1.SVNServer, obj, current client.Object) (bool, error) {
+	gvk, err := apiutil.GVKForObject(obj, r.Scheme)
+	if err != nil {
+		return false, err
+	}
+	kind := gvk.Kind
+	log = log.WithValues(kind+".Name", obj.GetName())
+	created, changed, err := r.apply(ctx, obj, current)
+	if err != nil {
+		log.Error(err, "Failed to apply "+kind)
+		if created {
+			r.recordEvent(s, corev1.EventTypeWarning, EventReasonCreateFailed, "Failed to create %s %s: %v", kind, obj.GetName(), err)
+		} else {
+			r.recordEvent(s, corev1.EventTypeWarning, EventReasonUpdateFailed, "Failed to update %s %s: %v", kind, obj.GetName(), err)
+		}
+		return false, err
+	}
+	switch {
+	case created:
+		log.Info("Created " + kind)
+		r.recordEvent(s, corev1.EventTypeNormal, EventReasonCreated, "Created %s %s", kind, obj.GetName())
+	case changed:
+		log.Info("Updated " + kind)
+		r.recordEvent(s, corev1.EventTypeNormal, EventReasonUpdated, "Updated %s %s", kind, obj.GetName())
+	}
+	return changed, nil
+}
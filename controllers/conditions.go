@@ -0,0 +1,71 @@
+/*
+Copyright 2024 markzhang.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	svnv1alpha1 "github.com/markzhang0928/svn-operator/api/v1alpha1"
+)
+
+// readinessConditions returns Ready, Progressing and Degraded conditions that share the reason and the message.
+// At most one of them is True.
+func readinessConditions(readyType, reason, message string) []metav1.Condition {
+	types := []string{svnv1alpha1.ConditionTypeReady, svnv1alpha1.ConditionTypeProgressing, svnv1alpha1.ConditionTypeDegraded}
+	conds := make([]metav1.Condition, 0, len(types))
+	for _, t := range types {
+		status := metav1.ConditionFalse
+		if t == readyType {
+			status = metav1.ConditionTrue
+		}
+		conds = append(conds, metav1.Condition{Type: t, Status: status, Reason: reason, Message: message})
+	}
+	return conds
+}
+
+// setConditions sets the conditions in conds and the observed generation to the generation of obj.
+// It returns true if any of them is changed.
+//
+// Older versions of the operator kept a history of conditions without statuses, which are read as
+// conditions whose statuses are empty. They are dropped here so that the status is converted to
+// the current format when it is updated next time.
+func setConditions(obj client.Object, conds *[]metav1.Condition, observedGeneration *int64, newConds ...metav1.Condition) bool {
+	changed := false
+	migrated := make([]metav1.Condition, 0, len(*conds))
+	for _, c := range *conds {
+		if c.Status == "" {
+			changed = true
+			continue
+		}
+		migrated = append(migrated, c)
+	}
+	*conds = migrated
+
+	for _, c := range newConds {
+		c.ObservedGeneration = obj.GetGeneration()
+		if meta.SetStatusCondition(conds, c) {
+			changed = true
+		}
+	}
+	if *observedGeneration != obj.GetGeneration() {
+		*observedGeneration = obj.GetGeneration()
+		changed = true
+	}
+	return changed
+}
@@ -0,0 +1,61 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	svnv1alpha1 "github.com/markzhang0928/svn-operator/api/v1alpha1"
+)
+
+var _ = Describe("Conditions", func() {
+	ctx := context.Background()
+
+	It("converts the status written by older versions", func() {
+		// Older versions kept a history of Synced and Failed conditions without statuses.
+		u := &svnv1alpha1.SVNUser{}
+		Expect(json.Unmarshal([]byte(`{
+			"metadata": {"namespace": "default", "name": "noel", "generation": 3},
+			"spec": {"svnServer": "svn"},
+			"status": {"conditions": [
+				{"type": "Failed", "reason": "group not found", "transitionTime": "2021-04-01T00:00:00Z"},
+				{"type": "Synced", "transitionTime": "2021-04-02T00:00:00Z"}
+			]}
+		}`), u)).To(Succeed())
+		Expect(u.Status.Conditions).To(HaveLen(2))
+
+		r, _ := newFakeReconciler(u)
+		f := &GeneratorFactory{
+			server: &svnv1alpha1.SVNServer{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "svn"}},
+			repos:  &svnv1alpha1.SVNRepositoryList{},
+			groups: &svnv1alpha1.SVNGroupList{},
+			users:  &svnv1alpha1.SVNUserList{Items: []svnv1alpha1.SVNUser{*u}},
+		}
+		Expect(r.updateChildStatuses(ctx, logr.Discard(), f, nil, nil)).To(Succeed())
+
+		Expect(r.Get(ctx, client.ObjectKeyFromObject(u), u)).To(Succeed())
+		Expect(u.Status.ObservedGeneration).To(BeEquivalentTo(3))
+		Expect(u.Status.Conditions).To(ConsistOf(
+			conditionMatcher(svnv1alpha1.ConditionTypeReady, metav1.ConditionTrue, svnv1alpha1.ReasonSynced),
+			conditionMatcher(svnv1alpha1.ConditionTypeProgressing, metav1.ConditionFalse, svnv1alpha1.ReasonSynced),
+			conditionMatcher(svnv1alpha1.ConditionTypeDegraded, metav1.ConditionFalse, svnv1alpha1.ReasonSynced),
+		))
+		for _, c := range u.Status.Conditions {
+			Expect(c.ObservedGeneration).To(BeEquivalentTo(3))
+			Expect(c.LastTransitionTime.IsZero()).To(BeFalse())
+		}
+
+		By("setting the same conditions again")
+		Expect(setConditions(u, &u.Status.Conditions, &u.Status.ObservedGeneration, childConditions("", nil)...)).To(BeFalse())
+	})
+})
+
+// conditionMatcher matches a condition with the type, the status and the reason.
+func conditionMatcher(typ string, status metav1.ConditionStatus, reason string) OmegaMatcher {
+	return And(HaveField("Type", typ), HaveField("Status", status), HaveField("Reason", reason))
+}
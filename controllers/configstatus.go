@@ -0,0 +1,97 @@
+/*
+Copyright 2024 markzhang.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	svnv1alpha1 "github.com/markzhang0928/svn-operator/api/v1alpha1"
+	"github.com/markzhang0928/svn-operator/pkg/serverupdater"
+)
+
+// stampConfig writes the hash of the configuration and the generation of the SVNServer into the ConfigMap,
+// and returns the hash. The generation of the current ConfigMap is kept if the hash has not changed,
+// so that changes to the SVNServer which do not affect the configuration do not rewrite the ConfigMap.
+// The Secret must not be changed afterwards since it is hashed too.
+func stampConfig(s *svnv1alpha1.SVNServer, current, cm *corev1.ConfigMap, secret *corev1.Secret) string {
+	hash := serverupdater.HashConfig(cm.Data, secret.Data)
+	generation := strconv.FormatInt(s.Generation, 10)
+	if g, ok := current.Data[ConfigMapKeyGeneration]; ok && current.Data[ConfigMapKeyConfigHash] == hash {
+		generation = g
+	}
+	cm.Data[ConfigMapKeyConfigHash] = hash
+	cm.Data[ConfigMapKeyGeneration] = generation
+	return hash
+}
+
+// appliedConfigConditions returns nil if every pod of the SVNServer has applied the configuration with the hash
+// without errors. Otherwise, it returns Progressing conditions if some pods have not applied it yet,
+// or Degraded conditions if some pods have failed to apply it.
+func (r *SVNServerReconciler) appliedConfigConditions(ctx context.Context, log logr.Logger, s *svnv1alpha1.SVNServer, hash string) ([]metav1.Condition, error) {
+	pods := &corev1.PodList{}
+	if err := r.List(ctx, pods, client.InNamespace(s.Namespace), client.MatchingLabels(r.labelsFor(s))); err != nil {
+		log.Error(err, "Failed to list pods")
+		return nil, err
+	}
+	if len(pods.Items) == 0 {
+		return readinessConditions(svnv1alpha1.ConditionTypeProgressing, svnv1alpha1.ReasonApplyingConfig, "Waiting for pods to be created"), nil
+	}
+	sort.Slice(pods.Items, func(i, j int) bool {
+		return pods.Items[i].Name < pods.Items[j].Name
+	})
+
+	var pending []metav1.Condition
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		status, err := r.fetchPodStatus(ctx, pod)
+		if err != nil {
+			log.Info("Server updater is not available; waiting for the configuration to be applied", "pod", pod.Name, "error", err.Error())
+			pending = readinessConditions(svnv1alpha1.ConditionTypeProgressing, svnv1alpha1.ReasonApplyingConfig,
+				fmt.Sprintf("Waiting for pod %s to report the applied configuration", pod.Name))
+			continue
+		}
+		if status.Config == nil || status.Config.Hash != hash {
+			pending = readinessConditions(svnv1alpha1.ConditionTypeProgressing, svnv1alpha1.ReasonApplyingConfig,
+				fmt.Sprintf("Waiting for pod %s to apply the configuration", pod.Name))
+			continue
+		}
+		if status.Config.Error != "" {
+			// Failures are reported before pending pods, since waiting for the others does not fix them.
+			return readinessConditions(svnv1alpha1.ConditionTypeDegraded, svnv1alpha1.ReasonApplyFailed,
+				fmt.Sprintf("Pod %s failed to apply the configuration: %s", pod.Name, status.Config.Error)), nil
+		}
+	}
+	return pending, nil
+}
+
+// unlessApplied returns configConds instead of conds if conds are Ready but the pods have not applied
+// the configuration, which configConds is non-nil for.
+func unlessApplied(conds, configConds []metav1.Condition) []metav1.Condition {
+	if configConds == nil || !meta.IsStatusConditionTrue(conds, svnv1alpha1.ConditionTypeReady) {
+		return conds
+	}
+	return configConds
+}
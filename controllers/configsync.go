@@ -0,0 +1,190 @@
+/*
+Copyright 2024 markzhang.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	svnv1alpha1 "github.com/markzhang0928/svn-operator/api/v1alpha1"
+)
+
+const (
+	// EnvConfigSync, EnvServerName and EnvServerNamespace make the entrypoint of the SVN server run
+	// the server updater so that it watches the ConfigMap and the Secret of the SVNServer through the API.
+	EnvConfigSync      = "SVN_CONFIG_SYNC"
+	EnvServerName      = "SVN_SERVER_NAME"
+	EnvServerNamespace = "SVN_SERVER_NAMESPACE"
+)
+
+// syncsConfigThroughAPI reports whether the server updater of the SVNServer watches its configuration through the API.
+func syncsConfigThroughAPI(s *svnv1alpha1.SVNServer) bool {
+	return s.Spec.ConfigSync == svnv1alpha1.ConfigSyncAPI
+}
+
+// configSyncServiceAccountOf returns the name of the ServiceAccount that is allowed to read the configuration,
+// which is a dedicated one named after the SVNServer unless spec.podTemplate.serviceAccountName is specified.
+// The default ServiceAccount is never used, since any pod in the namespace could read the Secret then.
+func configSyncServiceAccountOf(s *svnv1alpha1.SVNServer) string {
+	if s.Spec.PodTemplate.ServiceAccountName != "" {
+		return s.Spec.PodTemplate.ServiceAccountName
+	}
+	return s.Name
+}
+
+// reconcileConfigSyncRBAC applies the ServiceAccount, the Role and the RoleBinding that let the pods of the SVNServer
+// read its ConfigMap and Secret, or deletes them if the configuration is synced through volumes.
+// The ServiceAccount is only applied if the SVNServer does not specify its own.
+func (r *SVNServerReconciler) reconcileConfigSyncRBAC(ctx context.Context, log logr.Logger, s *svnv1alpha1.SVNServer) (bool, error) {
+	if !syncsConfigThroughAPI(s) {
+		if err := r.deleteOwned(ctx, log, s, &rbacv1.RoleBinding{}); err != nil {
+			return false, err
+		}
+		if err := r.deleteOwned(ctx, log, s, &rbacv1.Role{}); err != nil {
+			return false, err
+		}
+		return false, r.deleteOwned(ctx, log, s, &corev1.ServiceAccount{})
+	}
+	role, binding, err := r.configSyncRBACFor(s)
+	if err != nil {
+		log.Error(err, "Failed to compute desired Role and RoleBinding")
+		return false, err
+	}
+	saChanged := false
+	if s.Spec.PodTemplate.ServiceAccountName == "" {
+		saChanged, err = r.applyServiceAccount(ctx, log, s)
+	} else {
+		err = r.deleteOwned(ctx, log, s, &corev1.ServiceAccount{})
+	}
+	if err != nil {
+		return false, err
+	}
+	roleChanged, err := r.applyOwned(ctx, log, s, role, &rbacv1.Role{})
+	if err != nil {
+		return false, err
+	}
+	bindingChanged, err := r.applyOwned(ctx, log, s, binding, &rbacv1.RoleBinding{})
+	if err != nil {
+		return false, err
+	}
+	return saChanged || roleChanged || bindingChanged, nil
+}
+
+// applyServiceAccount applies the dedicated ServiceAccount of the SVNServer.
+// A ServiceAccount with the same name that the SVNServer does not control is never taken over,
+// since it would be granted access to the Secret and deleted with the SVNServer.
+func (r *SVNServerReconciler) applyServiceAccount(ctx context.Context, log logr.Logger, s *svnv1alpha1.SVNServer) (bool, error) {
+	current := &corev1.ServiceAccount{}
+	err := r.Get(ctx, client.ObjectKeyFromObject(s), current)
+	if err != nil && !errors.IsNotFound(err) {
+		return false, err
+	}
+	if err == nil && !metav1.IsControlledBy(current, s) {
+		return false, fmt.Errorf("ServiceAccount %s is not owned by the SVNServer; specify spec.podTemplate.serviceAccountName to use it", s.Name)
+	}
+	sa := &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      s.Name,
+			Namespace: s.Namespace,
+			Labels:    r.labelsFor(s),
+		},
+	}
+	if err := ctrl.SetControllerReference(s, sa, r.Scheme); err != nil {
+		log.Error(err, "Failed to compute desired ServiceAccount")
+		return false, err
+	}
+	return r.applyOwned(ctx, log, s, sa, &corev1.ServiceAccount{})
+}
+
+// configSyncRBACFor returns the Role that allows reading only the ConfigMap and the Secret of the SVNServer,
+// and the RoleBinding that grants it to the ServiceAccount of the pods.
+func (r *SVNServerReconciler) configSyncRBACFor(s *svnv1alpha1.SVNServer) (*rbacv1.Role, *rbacv1.RoleBinding, error) {
+	meta := metav1.ObjectMeta{
+		Name:      s.Name,
+		Namespace: s.Namespace,
+	}
+	role := &rbacv1.Role{
+		ObjectMeta: *meta.DeepCopy(),
+		Rules: []rbacv1.PolicyRule{{
+			APIGroups: []string{""},
+			Resources: []string{"configmaps", "secrets"},
+			// List and watch are restricted to the names by field selectors on metadata.name.
+			ResourceNames: []string{s.Name},
+			Verbs:         []string{"get", "list", "watch"},
+		}},
+	}
+	serviceAccount := configSyncServiceAccountOf(s)
+	if serviceAccount == "default" {
+		return nil, nil, fmt.Errorf("the default ServiceAccount may not read the Secret of the SVNServer")
+	}
+	binding := &rbacv1.RoleBinding{
+		ObjectMeta: *meta.DeepCopy(),
+		RoleRef: rbacv1.RoleRef{
+			APIGroup: rbacv1.GroupName,
+			Kind:     "Role",
+			Name:     role.Name,
+		},
+		Subjects: []rbacv1.Subject{{
+			Kind:      rbacv1.ServiceAccountKind,
+			Name:      serviceAccount,
+			Namespace: s.Namespace,
+		}},
+	}
+	for _, obj := range []metav1.Object{role, binding} {
+		if err := ctrl.SetControllerReference(s, obj, r.Scheme); err != nil {
+			return nil, nil, err
+		}
+	}
+	return role, binding, nil
+}
+
+// configureConfigSync replaces the volumes of the ConfigMap and the Secret with empty directories that
+// the server updater writes the configuration into, and tells the entrypoint what to watch.
+// The pods run as the ServiceAccount that is allowed to read the configuration.
+func configureConfigSync(s *svnv1alpha1.SVNServer, podSpec *corev1.PodSpec, c *corev1.Container) {
+	podSpec.ServiceAccountName = configSyncServiceAccountOf(s)
+	for _, m := range []struct {
+		volume, path string
+	}{
+		{VolumeNameConfig, VolumePathConfig},
+		{VolumeNameAuth, VolumePathAuth},
+	} {
+		setVolume(podSpec, corev1.Volume{
+			Name: m.volume,
+			VolumeSource: corev1.VolumeSource{
+				EmptyDir: &corev1.EmptyDirVolumeSource{Medium: corev1.StorageMediumMemory},
+			},
+		})
+		setVolumeMount(c, corev1.VolumeMount{
+			Name:      m.volume,
+			MountPath: m.path,
+		})
+	}
+	c.Env = append(c.Env,
+		corev1.EnvVar{Name: EnvConfigSync, Value: svnv1alpha1.ConfigSyncAPI},
+		corev1.EnvVar{Name: EnvServerName, Value: s.Name},
+		corev1.EnvVar{Name: EnvServerNamespace, Value: s.Namespace},
+	)
+}
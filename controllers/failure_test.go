@@ -0,0 +1,92 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	svnv1alpha1 "github.com/markzhang0928/svn-operator/api/v1alpha1"
+)
+
+var _ = Describe("Failures", func() {
+	ctx := context.Background()
+	key := types.NamespacedName{Namespace: "default", Name: "svn"}
+	var r *SVNServerReconciler
+	var recorder *record.FakeRecorder
+
+	newServer := func() *svnv1alpha1.SVNServer {
+		return &svnv1alpha1.SVNServer{
+			ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name, Generation: 1},
+		}
+	}
+	degradedCondition := func() *metav1.Condition {
+		s := &svnv1alpha1.SVNServer{}
+		ExpectWithOffset(1, r.Get(ctx, key, s)).To(Succeed())
+		return meta.FindStatusCondition(s.Status.Conditions, svnv1alpha1.ConditionTypeDegraded)
+	}
+
+	It("records the objects that are created", func() {
+		r, recorder = newFakeReconciler(newServer())
+		Expect(r.Reconcile(ctx, reconcile.Request{NamespacedName: key})).Error().NotTo(HaveOccurred())
+		Expect(recordedEvents(recorder)).To(ContainElement("Normal Created Created Service svn"))
+		Expect(degradedCondition().Status).To(Equal(metav1.ConditionFalse))
+	})
+
+	It("records failures to create objects", func() {
+		r, recorder = newFakeReconciler(newServer())
+		failingPatches(r, errors.New("quota exceeded"), func(obj client.Object) bool {
+			_, ok := obj.(*corev1.Service)
+			return ok
+		})
+		Expect(r.Reconcile(ctx, reconcile.Request{NamespacedName: key})).Error().To(MatchError("quota exceeded"))
+		Expect(recordedEvents(recorder)).To(ContainElement("Warning CreateFailed Failed to create Service svn: quota exceeded"))
+		Expect(degradedCondition()).To(HaveValue(And(
+			HaveField("Status", metav1.ConditionTrue),
+			HaveField("Reason", svnv1alpha1.ReasonServiceFailed),
+			HaveField("Message", "quota exceeded"),
+			HaveField("ObservedGeneration", BeEquivalentTo(1)),
+		)))
+	})
+
+	It("records failures to update the configuration", func() {
+		r, recorder = newFakeReconciler(newServer())
+		Expect(r.Reconcile(ctx, reconcile.Request{NamespacedName: key})).Error().NotTo(HaveOccurred())
+		recordedEvents(recorder)
+
+		failingPatches(r, errors.New("etcd is unavailable"), func(obj client.Object) bool {
+			_, ok := obj.(*corev1.ConfigMap)
+			return ok
+		})
+		Expect(r.Reconcile(ctx, reconcile.Request{NamespacedName: key})).Error().To(HaveOccurred())
+		Expect(recordedEvents(recorder)).To(ContainElement("Warning UpdateFailed Failed to update ConfigMap svn: etcd is unavailable"))
+		Expect(degradedCondition()).To(HaveValue(And(
+			HaveField("Status", metav1.ConditionTrue),
+			HaveField("Reason", svnv1alpha1.ReasonConfigMapFailed),
+		)))
+	})
+
+	It("records invalid configurations of the SVNServer", func() {
+		s := newServer()
+		s.Spec.Authentication = &svnv1alpha1.AuthenticationSpec{
+			Mode: svnv1alpha1.AuthenticationModeLDAP,
+			LDAP: &svnv1alpha1.LDAPSpec{URL: "ldap://ldap.example.com/ou=people,dc=example,dc=com", BindSecretName: "ldap-bind"},
+		}
+		bindSecret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: "ldap-bind"}}
+		r, recorder = newFakeReconciler(s, bindSecret)
+		Expect(r.Reconcile(ctx, reconcile.Request{NamespacedName: key})).Error().To(HaveOccurred())
+		Expect(degradedCondition()).To(HaveValue(And(
+			HaveField("Status", metav1.ConditionTrue),
+			HaveField("Reason", svnv1alpha1.ReasonSecretFailed),
+			HaveField("Message", ContainSubstring(`has no key "bindDN"`)),
+		)))
+	})
+})
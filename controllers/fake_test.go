@@ -0,0 +1,88 @@
+package controllers
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	svnv1alpha1 "github.com/markzhang0928/svn-operator/api/v1alpha1"
+)
+
+// newFakeReconciler returns an SVNServerReconciler backed by the fake client with the same indexes as the manager,
+// and the recorder of its events. The fake client does not support server-side apply, so applied objects are
+// created or replaced as a whole, which is enough as long as nobody else changes them.
+func newFakeReconciler(objs ...client.Object) (*SVNServerReconciler, *record.FakeRecorder) {
+	c := fake.NewClientBuilder().
+		WithScheme(scheme.Scheme).
+		WithObjects(objs...).
+		WithStatusSubresource(&svnv1alpha1.SVNServer{}, &svnv1alpha1.SVNRepository{}, &svnv1alpha1.SVNGroup{}, &svnv1alpha1.SVNUser{}).
+		WithIndex(&svnv1alpha1.SVNRepository{}, IndexKeySVNServer, func(obj client.Object) []string {
+			return []string{obj.(*svnv1alpha1.SVNRepository).Spec.SVNServer}
+		}).
+		WithIndex(&svnv1alpha1.SVNGroup{}, IndexKeySVNServer, func(obj client.Object) []string {
+			return []string{obj.(*svnv1alpha1.SVNGroup).Spec.SVNServer}
+		}).
+		WithIndex(&svnv1alpha1.SVNUser{}, IndexKeySVNServer, func(obj client.Object) []string {
+			return []string{obj.(*svnv1alpha1.SVNUser).Spec.SVNServer}
+		}).
+		WithIndex(&svnv1alpha1.SVNUser{}, IndexKeyPasswordSecretRef, func(obj client.Object) []string {
+			if ref := passwordSecretRefOf(obj.(*svnv1alpha1.SVNUser)); ref != nil {
+				return []string{ref.Name}
+			}
+			return nil
+		}).
+		WithInterceptorFuncs(interceptor.Funcs{Patch: applyAsUpdate}).
+		Build()
+	recorder := record.NewFakeRecorder(100)
+	return &SVNServerReconciler{
+		Client:   c,
+		Log:      logr.Discard(),
+		Scheme:   scheme.Scheme,
+		Recorder: recorder,
+	}, recorder
+}
+
+// recordedEvents returns the events that have been recorded since the last call.
+func recordedEvents(recorder *record.FakeRecorder) []string {
+	var events []string
+	for len(recorder.Events) > 0 {
+		events = append(events, <-recorder.Events)
+	}
+	return events
+}
+
+// failingPatches makes patches to objects fail with err if fail returns true for them.
+func failingPatches(r *SVNServerReconciler, err error, fail func(obj client.Object) bool) {
+	r.Client = interceptor.NewClient(r.Client.(client.WithWatch), interceptor.Funcs{
+		Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+			if fail(obj) {
+				return err
+			}
+			return c.Patch(ctx, obj, patch, opts...)
+		},
+	})
+}
+
+// applyAsUpdate emulates server-side apply by creating the object or replacing the existing one.
+func applyAsUpdate(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if patch.Type() != types.ApplyPatchType {
+		return c.Patch(ctx, obj, patch, opts...)
+	}
+	current := obj.DeepCopyObject().(client.Object)
+	err := c.Get(ctx, client.ObjectKeyFromObject(obj), current)
+	if apierrors.IsNotFound(err) {
+		return c.Create(ctx, obj)
+	}
+	if err != nil {
+		return err
+	}
+	obj.SetResourceVersion(current.GetResourceVersion())
+	return c.Update(ctx, obj)
+}
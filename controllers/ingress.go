@@ -0,0 +1,243 @@
+/*
+Copyright 2024 markzhang.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"strings"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+
+	svnv1alpha1 "github.com/markzhang0928/svn-operator/api/v1alpha1"
+)
+
+// Here is a list of annotations that cert-manager issues certificates of Ingresses by.
+const (
+	AnnotationCertManagerIssuer        = "cert-manager.io/issuer"
+	AnnotationCertManagerClusterIssuer = "cert-manager.io/cluster-issuer"
+)
+
+// reconcileIngress applies the Ingress or the HTTPRoute of the SVNServer, deletes the one that is no longer
+// specified, and returns the external URL of the repositories.
+func (r *SVNServerReconciler) reconcileIngress(ctx context.Context, log logr.Logger, s *svnv1alpha1.SVNServer) (url string, changed bool, err error) {
+	if s.Spec.Ingress != nil {
+		ing, err := r.ingressFor(s)
+		if err != nil {
+			log.Error(err, "Failed to compute desired Ingress")
+			return "", false, err
+		}
+		changed, err = r.applyOwned(ctx, log, s, ing, &networkingv1.Ingress{})
+		if err != nil {
+			return "", false, err
+		}
+		scheme := "http"
+		if s.Spec.Ingress.TLS != nil {
+			scheme = "https"
+		}
+		url = scheme + "://" + s.Spec.Ingress.Host + s.Spec.URLPrefix()
+	} else if err := r.deleteOwned(ctx, log, s, &networkingv1.Ingress{}); err != nil {
+		return "", false, err
+	}
+
+	if s.Spec.HTTPRoute != nil {
+		route, err := r.httpRouteFor(s)
+		if err != nil {
+			log.Error(err, "Failed to compute desired HTTPRoute")
+			return "", false, err
+		}
+		routeChanged, err := r.applyOwned(ctx, log, s, route, &gatewayv1.HTTPRoute{})
+		if err != nil {
+			return "", false, err
+		}
+		changed = changed || routeChanged
+		scheme := "http"
+		if s.Spec.HTTPRoute.HTTPS {
+			scheme = "https"
+		}
+		url = scheme + "://" + s.Spec.HTTPRoute.Host + s.Spec.URLPrefix()
+	} else if err := r.deleteOwned(ctx, log, s, &gatewayv1.HTTPRoute{}); err != nil {
+		return "", false, err
+	}
+	return url, changed, nil
+}
+
+// deleteOwned deletes the object named after the SVNServer if it is controlled by the SVNServer.
+// Kinds whose CRDs are not installed are ignored, since there is nothing to delete.
+func (r *SVNServerReconciler) deleteOwned(ctx context.Context, log logr.Logger, s *svnv1alpha1.SVNServer, obj client.Object) error {
+	err := r.Get(ctx, client.ObjectKeyFromObject(s), obj)
+	if errors.IsNotFound(err) || meta.IsNoMatchError(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !metav1.IsControlledBy(obj, s) {
+		return nil
+	}
+	gvk, err := apiutil.GVKForObject(obj, r.Scheme)
+	if err != nil {
+		return err
+	}
+	kind := gvk.Kind
+	if err := r.Delete(ctx, obj, client.Preconditions{UID: ptr.To(obj.GetUID())}); err != nil && !errors.IsNotFound(err) {
+		log.Error(err, "Failed to delete "+kind, kind+".Name", obj.GetName())
+		return err
+	}
+	log.Info("Deleted "+kind, kind+".Name", obj.GetName())
+	r.recordEvent(s, corev1.EventTypeNormal, EventReasonDeleted, "Deleted %s %s", kind, obj.GetName())
+	return nil
+}
+
+// ingressFor returns the Ingress that routes the URL prefix of spec.ingress.host to the client-facing Service.
+func (r *SVNServerReconciler) ingressFor(s *svnv1alpha1.SVNServer) (*networkingv1.Ingress, error) {
+	spec := s.Spec.Ingress
+	annotations := map[string]string{}
+	for k, v := range spec.Annotations {
+		annotations[k] = v
+	}
+	ing := &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      s.Name,
+			Namespace: s.Namespace,
+		},
+		Spec: networkingv1.IngressSpec{
+			Rules: []networkingv1.IngressRule{{
+				Host: spec.Host,
+				IngressRuleValue: networkingv1.IngressRuleValue{
+					HTTP: &networkingv1.HTTPIngressRuleValue{
+						Paths: []networkingv1.HTTPIngressPath{{
+							Path:     s.Spec.URLPrefix(),
+							PathType: ptr.To(networkingv1.PathTypePrefix),
+							Backend: networkingv1.IngressBackend{
+								Service: &networkingv1.IngressServiceBackend{
+									Name: s.Name + svnv1alpha1.ClientServiceSuffix,
+									Port: networkingv1.ServiceBackendPort{Name: svnv1alpha1.PortNameHTTP},
+								},
+							},
+						}},
+					},
+				},
+			}},
+		},
+	}
+	if spec.IngressClassName != "" {
+		ing.Spec.IngressClassName = ptr.To(spec.IngressClassName)
+	}
+	if tls := spec.TLS; tls != nil {
+		secretName := tls.SecretName
+		if issuer := tls.IssuerRef; issuer != nil {
+			if secretName == "" {
+				secretName = s.Name + svnv1alpha1.TLSSecretSuffix
+			}
+			if issuer.Kind == "ClusterIssuer" {
+				annotations[AnnotationCertManagerClusterIssuer] = issuer.Name
+			} else {
+				annotations[AnnotationCertManagerIssuer] = issuer.Name
+			}
+		}
+		ing.Spec.TLS = []networkingv1.IngressTLS{{
+			Hosts:      []string{spec.Host},
+			SecretName: secretName,
+		}}
+	}
+	if len(annotations) > 0 {
+		ing.Annotations = annotations
+	}
+	if err := ctrl.SetControllerReference(s, ing, r.Scheme); err != nil {
+		return nil, err
+	}
+	return ing, nil
+}
+
+// httpRouteFor returns the HTTPRoute that routes the URL prefix of spec.httpRoute.host to the client-facing Service.
+func (r *SVNServerReconciler) httpRouteFor(s *svnv1alpha1.SVNServer) (*gatewayv1.HTTPRoute, error) {
+	spec := s.Spec.HTTPRoute
+	// Gateway API prefixes match whole path elements and may not end with `/` unless they are `/`.
+	prefix := strings.TrimSuffix(s.Spec.URLPrefix(), "/")
+	if prefix == "" {
+		prefix = "/"
+	}
+	route := &gatewayv1.HTTPRoute{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        s.Name,
+			Namespace:   s.Namespace,
+			Annotations: spec.Annotations,
+		},
+		Spec: gatewayv1.HTTPRouteSpec{
+			Hostnames: []gatewayv1.Hostname{gatewayv1.Hostname(spec.Host)},
+			Rules: []gatewayv1.HTTPRouteRule{{
+				Matches: []gatewayv1.HTTPRouteMatch{{
+					Path: &gatewayv1.HTTPPathMatch{
+						Type:  ptr.To(gatewayv1.PathMatchPathPrefix),
+						Value: ptr.To(prefix),
+					},
+				}},
+				BackendRefs: []gatewayv1.HTTPBackendRef{{
+					BackendRef: gatewayv1.BackendRef{
+						BackendObjectReference: gatewayv1.BackendObjectReference{
+							Group: ptr.To(gatewayv1.Group("")),
+							Kind:  ptr.To(gatewayv1.Kind("Service")),
+							Name:  gatewayv1.ObjectName(s.Name + svnv1alpha1.ClientServiceSuffix),
+							Port:  ptr.To(gatewayv1.PortNumber(clientHTTPPortOf(s))),
+						},
+						Weight: ptr.To[int32](1),
+					},
+				}},
+			}},
+		},
+	}
+	for _, ref := range spec.ParentRefs {
+		parent := gatewayv1.ParentReference{
+			Group: ptr.To(gatewayv1.Group(gatewayv1.GroupName)),
+			Kind:  ptr.To(gatewayv1.Kind("Gateway")),
+			Name:  gatewayv1.ObjectName(ref.Name),
+		}
+		if ref.Namespace != "" {
+			parent.Namespace = ptr.To(gatewayv1.Namespace(ref.Namespace))
+		}
+		if ref.SectionName != "" {
+			parent.SectionName = ptr.To(gatewayv1.SectionName(ref.SectionName))
+		}
+		route.Spec.ParentRefs = append(route.Spec.ParentRefs, parent)
+	}
+	if err := ctrl.SetControllerReference(s, route, r.Scheme); err != nil {
+		return nil, err
+	}
+	return route, nil
+}
+
+// clientHTTPPortOf returns the HTTP port of the client-facing Service of the SVNServer.
+func clientHTTPPortOf(s *svnv1alpha1.SVNServer) int32 {
+	if s.Spec.Service != nil {
+		for _, p := range s.Spec.Service.Ports {
+			if p.Name == svnv1alpha1.PortNameHTTP {
+				return p.Port
+			}
+		}
+	}
+	return 80
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2024 markzhang.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	svnv1alpha1 "github.com/markzhang0928/svn-operator/api/v1alpha1"
+	"github.com/markzhang0928/svn-operator/pkg/svnconfig"
+)
+
+const (
+	// VolumeNameLDAPBind is a volume that the Secret with the bind DN and its password is mounted from.
+	VolumeNameLDAPBind = "ldap-bind"
+	VolumePathLDAPBind = "/etc/svn-ldap/bind/"
+	// VolumeNameLDAPCA is a volume that the Secret with CA certificates of the LDAP server is mounted from.
+	VolumeNameLDAPCA = "ldap-ca"
+	VolumePathLDAPCA = "/etc/svn-ldap/ca/"
+
+	// IndexKeyLDAPSecretName indexes SVNServers by the Secrets that their LDAP configurations refer to.
+	IndexKeyLDAPSecretName = ".spec.authentication.ldap.secretNames"
+)
+
+// ldapBindDNOf reads the DN to bind as from the bind Secret of the SVNServer.
+// It returns an empty DN if the SVNServer does not authenticate users with LDAP or binds anonymously.
+// The DN is rendered into the configuration of Apache, while the password is read by Apache from the mounted Secret.
+func (r *SVNServerReconciler) ldapBindDNOf(ctx context.Context, s *svnv1alpha1.SVNServer) (string, error) {
+	ldap := s.Spec.LDAP()
+	if ldap == nil || ldap.BindSecretName == "" {
+		return "", nil
+	}
+	secret := &corev1.Secret{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: s.Namespace, Name: ldap.BindSecretName}, secret); err != nil {
+		return "", err
+	}
+	dn := strings.TrimSpace(string(secret.Data[svnv1alpha1.LDAPSecretKeyBindDN]))
+	if dn == "" {
+		return "", fmt.Errorf("Secret %q has no key %q", ldap.BindSecretName, svnv1alpha1.LDAPSecretKeyBindDN)
+	}
+	if strings.ContainsAny(dn, "\"\r\n") {
+		return "", fmt.Errorf("key %q of Secret %q must not contain quotes or newlines", svnv1alpha1.LDAPSecretKeyBindDN, ldap.BindSecretName)
+	}
+	return dn, nil
+}
+
+// apacheLDAPOf converts the LDAP configuration of an SVNServer into the one of mod_authnz_ldap.
+func apacheLDAPOf(ldap *svnv1alpha1.LDAPSpec, bindDN string) *svnconfig.LDAP {
+	attr := ldap.UserAttribute
+	if attr == "" {
+		attr = "uid"
+	}
+	conf := &svnconfig.LDAP{
+		URL:      fmt.Sprintf("%s?%s?sub?%s", ldap.URL, attr, ldap.UserFilter),
+		StartTLS: ldap.StartTLS,
+		BindDN:   bindDN,
+	}
+	if bindDN != "" {
+		conf.BindPasswordFile = path.Join(VolumePathLDAPBind, svnv1alpha1.LDAPSecretKeyBindPassword)
+	}
+	if ldap.CASecretName != "" {
+		conf.CACertFile = path.Join(VolumePathLDAPCA, svnv1alpha1.LDAPSecretKeyCACert)
+	}
+	return conf
+}
+
+// configureLDAP mounts the Secrets that the LDAP configuration refers to into the SVN container.
+func configureLDAP(ldap *svnv1alpha1.LDAPSpec, podSpec *corev1.PodSpec, c *corev1.Container) {
+	defaultMode := corev1.SecretVolumeSourceDefaultMode
+	mounts := []struct {
+		volume, path, secret string
+	}{
+		{VolumeNameLDAPBind, VolumePathLDAPBind, ldap.BindSecretName},
+		{VolumeNameLDAPCA, VolumePathLDAPCA, ldap.CASecretName},
+	}
+	for _, m := range mounts {
+		if m.secret == "" {
+			continue
+		}
+		setVolume(podSpec, corev1.Volume{
+			Name: m.volume,
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName:  m.secret,
+					DefaultMode: &defaultMode,
+				},
+			},
+		})
+		setVolumeMount(c, corev1.VolumeMount{
+			Name:      m.volume,
+			MountPath: m.path,
+			ReadOnly:  true,
+		})
+	}
+}
+
+// ldapSecretNamesOf returns the names of the Secrets that the LDAP configuration of the SVNServer refers to.
+func ldapSecretNamesOf(s *svnv1alpha1.SVNServer) []string {
+	ldap := s.Spec.LDAP()
+	if ldap == nil {
+		return nil
+	}
+	var names []string
+	for _, name := range []string{ldap.BindSecretName, ldap.CASecretName} {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// ldapSecretEnqueuer enqueues SVNServers whose LDAP configurations refer to the Secret,
+// so that changes to the bind DN take effect.
+func ldapSecretEnqueuer(mgr ctrl.Manager) handler.MapFunc {
+	return func(ctx context.Context, obj client.Object) []reconcile.Request {
+		servers := &svnv1alpha1.SVNServerList{}
+		err := mgr.GetClient().List(ctx, servers, client.InNamespace(obj.GetNamespace()), client.MatchingFields{IndexKeyLDAPSecretName: obj.GetName()})
+		if err != nil {
+			mgr.GetLogger().Error(err, "Failed to list SVNServers", "Secret.Namespace", obj.GetNamespace(), "Secret.Name", obj.GetName())
+			return []reconcile.Request{}
+		}
+		requests := make([]reconcile.Request, 0, len(servers.Items))
+		for i := range servers.Items {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Namespace: obj.GetNamespace(), Name: servers.Items[i].Name},
+			})
+		}
+		return requests
+	}
+}
@@ -0,0 +1,290 @@
+/*
+Copyright 2024 markzhang.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	svnv1alpha1 "github.com/markzhang0928/svn-operator/api/v1alpha1"
+	"github.com/markzhang0928/svn-operator/pkg/svnconfig"
+)
+
+// GeneratedPasswordLength is the length of passwords generated by the operator.
+const GeneratedPasswordLength = 24
+
+// EventReasonPasswordGenerated is a reason of events recorded on SVNUsers when their passwords are generated.
+const EventReasonPasswordGenerated = "PasswordGenerated"
+
+// passwordSecretRefOf returns the reference to the Secret that holds the password of the SVNUser,
+// or nil if the password is specified by EncryptedPassword.
+func passwordSecretRefOf(u *svnv1alpha1.SVNUser) *svnv1alpha1.PasswordSecretRef {
+	if u.Spec.PasswordSecretRef != nil {
+		return u.Spec.PasswordSecretRef
+	}
+	if u.Spec.EncryptedPassword != "" {
+		return nil
+	}
+	return &svnv1alpha1.PasswordSecretRef{
+		Name: u.Name + svnv1alpha1.GeneratedPasswordSecretSuffix,
+		Key:  svnv1alpha1.DefaultPasswordSecretKey,
+		Type: svnv1alpha1.PasswordTypePlaintext,
+	}
+}
+
+// generatePasswords generates passwords of SVNUsers that have no password, or that are annotated with
+// AnnotationRegeneratePassword, into Secrets owned by the SVNUsers.
+// Errors about individual SVNUsers are reported as conditions of them through the missing Secrets,
+// so only errors on the API server are returned.
+func (r *SVNServerReconciler) generatePasswords(ctx context.Context, log logr.Logger, users *svnv1alpha1.SVNUserList) error {
+	for i := range users.Items {
+		u := &users.Items[i]
+		if u.Spec.PasswordSecretRef != nil || u.Spec.EncryptedPassword != "" {
+			continue
+		}
+		if err := r.generatePasswordOf(ctx, log, u); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *SVNServerReconciler) generatePasswordOf(ctx context.Context, log logr.Logger, u *svnv1alpha1.SVNUser) error {
+	ref := passwordSecretRefOf(u)
+	log = log.WithValues("SVNUser.Name", u.Name, "Secret.Name", ref.Name)
+	_, regenerate := u.Annotations[svnv1alpha1.AnnotationRegeneratePassword]
+
+	secret := &corev1.Secret{}
+	err := r.Get(ctx, types.NamespacedName{Name: ref.Name, Namespace: u.Namespace}, secret)
+	found := err == nil
+	if err != nil && !errors.IsNotFound(err) {
+		log.Error(err, "Failed to get Secret")
+		return err
+	}
+	if found && !metav1.IsControlledBy(secret, u) {
+		// Never overwrite Secrets that the operator has not created.
+		log.Info("Secret is not owned by SVNUser; not generating password")
+		return nil
+	}
+	if found && !regenerate && len(secret.Data[ref.Key]) > 0 {
+		return nil
+	}
+
+	password, err := svnconfig.GeneratePassword(GeneratedPasswordLength)
+	if err != nil {
+		return err
+	}
+	desired := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      ref.Name,
+			Namespace: u.Namespace,
+		},
+		Type: corev1.SecretTypeBasicAuth,
+		Data: map[string][]byte{
+			corev1.BasicAuthUsernameKey: []byte(u.Name),
+			corev1.BasicAuthPasswordKey: []byte(password),
+		},
+	}
+	if err := ctrl.SetControllerReference(u, desired, r.Scheme); err != nil {
+		return err
+	}
+	if _, _, err := r.apply(ctx, desired, secret); err != nil {
+		log.Error(err, "Failed to write generated password")
+		return err
+	}
+	log.Info("Generated password")
+	if r.Recorder != nil {
+		r.Recorder.Eventf(u, corev1.EventTypeNormal, EventReasonPasswordGenerated, "Generated password into Secret %s", ref.Name)
+	}
+
+	if regenerate {
+		patch := client.MergeFrom(u.DeepCopy())
+		delete(u.Annotations, svnv1alpha1.AnnotationRegeneratePassword)
+		if err := r.Patch(ctx, u, patch); err != nil {
+			log.Error(err, "Failed to remove annotation from SVNUser")
+			return err
+		}
+	}
+	return nil
+}
+
+// passwordSecretsOf returns Secrets that the SVNUsers refer to, keyed by their names.
+// Secrets that do not exist are omitted.
+func (r *SVNServerReconciler) passwordSecretsOf(ctx context.Context, users *svnv1alpha1.SVNUserList) (map[string]*corev1.Secret, error) {
+	secrets := map[string]*corev1.Secret{}
+	for i := range users.Items {
+		u := &users.Items[i]
+		ref := passwordSecretRefOf(u)
+		if ref == nil {
+			continue
+		}
+		if _, ok := secrets[ref.Name]; ok {
+			continue
+		}
+		secret := &corev1.Secret{}
+		err := r.Get(ctx, types.NamespacedName{Name: ref.Name, Namespace: u.Namespace}, secret)
+		if errors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		secrets[ref.Name] = secret
+	}
+	return secrets, nil
+}
+
+// resolveUsers computes hashes of passwords of SVNUsers, and finds SVNUsers that are invalid since their passwords
+// cannot be read. SVNUsers that belong to SVNGroups which do not exist or are invalid are recorded in
+// invalidMemberships; they lose only those memberships, since invalid groups are not written at all.
+// This must be called after invalidGroups is computed.
+func (f *GeneratorFactory) resolveUsers() {
+	if f.invalidUsers != nil {
+		// Hashing passwords is expensive, so they are computed only once.
+		return
+	}
+	known := make(map[string]bool, len(f.groups.Items))
+	for i := range f.groups.Items {
+		known[f.groups.Items[i].Name] = true
+	}
+	f.invalidUsers = map[string]error{}
+	f.invalidMemberships = map[string]error{}
+	f.hashes = map[string]string{}
+	f.passwords = map[string]string{}
+	for i := range f.users.Items {
+		u := &f.users.Items[i]
+		if err := f.checkGroupsOf(u, known); err != nil {
+			f.invalidMemberships[u.Name] = failure(svnv1alpha1.ReasonInvalidGroup, err)
+		}
+		if f.server.Spec.LDAP() != nil {
+			// Users are authenticated with LDAP, so their passwords are neither known nor needed.
+			continue
+		}
+		hash, password, err := f.passwordOf(u)
+		if err != nil {
+			f.invalidUsers[u.Name] = failure(svnv1alpha1.ReasonInvalidPassword, err)
+			continue
+		}
+		f.hashes[u.Name] = hash
+		if password != "" {
+			f.passwords[u.Name] = password
+		}
+	}
+}
+
+func (f *GeneratorFactory) checkGroupsOf(u *svnv1alpha1.SVNUser, known map[string]bool) error {
+	for _, ref := range u.Spec.Groups {
+		if !known[ref.Name] {
+			return fmt.Errorf("group %q does not exist; the membership is ignored", ref.Name)
+		}
+		if _, ok := f.invalidGroups[ref.Name]; ok {
+			return fmt.Errorf("group %q is invalid; the membership is ignored", ref.Name)
+		}
+	}
+	return nil
+}
+
+// userError returns the reason why the SVNUser is not written into configuration files as specified, or nil.
+// Users that are not written at all take precedence over those that only lose some memberships.
+func (f *GeneratorFactory) userError(name string) error {
+	if err, ok := f.invalidUsers[name]; ok {
+		return err
+	}
+	return f.invalidMemberships[name]
+}
+
+// passwordOf returns the hash of the password of the SVNUser that is written into AuthUserFile,
+// and the plaintext password for svnserve if it is known and can be written into its password database.
+func (f *GeneratorFactory) passwordOf(u *svnv1alpha1.SVNUser) (hash, plaintext string, err error) {
+	ref := passwordSecretRefOf(u)
+	if ref == nil {
+		return u.Spec.EncryptedPassword, "", nil
+	}
+	if u.Spec.EncryptedPassword != "" {
+		return "", "", fmt.Errorf("encryptedPassword and passwordSecretRef cannot be specified at the same time")
+	}
+
+	secret, ok := f.secrets[ref.Name]
+	if !ok {
+		return "", "", fmt.Errorf("Secret %q does not exist", ref.Name)
+	}
+	key := ref.Key
+	if key == "" {
+		key = svnv1alpha1.DefaultPasswordSecretKey
+	}
+	value, ok := secret.Data[key]
+	if !ok {
+		return "", "", fmt.Errorf("Secret %q has no key %q", ref.Name, key)
+	}
+	// Files often end with newlines, which are hardly intended to be parts of passwords.
+	password := strings.TrimRight(string(value), "\r\n")
+	if password == "" {
+		return "", "", fmt.Errorf("key %q of Secret %q is empty", key, ref.Name)
+	}
+
+	if ref.Type == svnv1alpha1.PasswordTypeHash {
+		if err := svnconfig.ValidatePasswordHash(password); err != nil {
+			return "", "", fmt.Errorf("key %q of Secret %q is not a valid hash: %w", key, ref.Name, err)
+		}
+		return password, "", nil
+	}
+	// The password database of svnserve is line-oriented and trims values, so such passwords are not written.
+	if password == strings.TrimSpace(password) && !strings.ContainsAny(password, "\r\n") {
+		plaintext = password
+	}
+	if prev, ok := f.previousHashes[u.Name]; ok && svnconfig.PasswordMatches(prev, password) {
+		return prev, plaintext, nil
+	}
+	hash, err = svnconfig.HashPassword(password)
+	return hash, plaintext, err
+}
+
+// passwordSecretEnqueuer enqueues SVNServers of SVNUsers that refer to the Secret,
+// so that changes to passwords take effect.
+func passwordSecretEnqueuer(mgr ctrl.Manager) handler.MapFunc {
+	return func(ctx context.Context, obj client.Object) []reconcile.Request {
+		users := &svnv1alpha1.SVNUserList{}
+		err := mgr.GetClient().List(ctx, users, client.InNamespace(obj.GetNamespace()), client.MatchingFields{IndexKeyPasswordSecretRef: obj.GetName()})
+		if err != nil {
+			mgr.GetLogger().Error(err, "Failed to list SVNUsers", "Secret.Namespace", obj.GetNamespace(), "Secret.Name", obj.GetName())
+			return []reconcile.Request{}
+		}
+		seen := map[string]bool{}
+		requests := []reconcile.Request{}
+		for i := range users.Items {
+			server := users.Items[i].Spec.SVNServer
+			if seen[server] {
+				continue
+			}
+			seen[server] = true
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Namespace: obj.GetNamespace(), Name: server},
+			})
+		}
+		return requests
+	}
+}
@@ -0,0 +1,165 @@
+package controllers
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	svnv1alpha1 "github.com/markzhang0928/svn-operator/api/v1alpha1"
+	"github.com/markzhang0928/svn-operator/pkg/svnconfig"
+)
+
+var _ = Describe("Passwords", func() {
+	ctx := context.Background()
+
+	userWithSecret := func(passwordType string) *svnv1alpha1.SVNUser {
+		return &svnv1alpha1.SVNUser{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "noel", UID: "noel-uid"},
+			Spec: svnv1alpha1.SVNUserSpec{
+				SVNServer:         "svn",
+				PasswordSecretRef: &svnv1alpha1.PasswordSecretRef{Name: "noel", Key: "password", Type: passwordType},
+			},
+		}
+	}
+	factoryWith := func(password string) *GeneratorFactory {
+		return &GeneratorFactory{
+			server: &svnv1alpha1.SVNServer{},
+			secrets: map[string]*corev1.Secret{
+				"noel": {Data: map[string][]byte{"password": []byte(password)}},
+			},
+		}
+	}
+
+	Describe("passwordOf", func() {
+		It("hashes plaintext passwords and keeps them for svnserve", func() {
+			hash, plaintext, err := factoryWith("s3cret\n").passwordOf(userWithSecret(svnv1alpha1.PasswordTypePlaintext))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(plaintext).To(Equal("s3cret"))
+			Expect(svnconfig.PasswordMatches(hash, "s3cret")).To(BeTrue())
+		})
+
+		It("reuses the previous hash if the password still matches", func() {
+			prev, err := svnconfig.HashPassword("s3cret")
+			Expect(err).NotTo(HaveOccurred())
+			f := factoryWith("s3cret")
+			f.previousHashes = map[string]string{"noel": prev}
+			hash, plaintext, err := f.passwordOf(userWithSecret(svnv1alpha1.PasswordTypePlaintext))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(hash).To(Equal(prev))
+			Expect(plaintext).To(Equal("s3cret"))
+
+			By("changing the password")
+			f = factoryWith("changed")
+			f.previousHashes = map[string]string{"noel": prev}
+			hash, _, err = f.passwordOf(userWithSecret(svnv1alpha1.PasswordTypePlaintext))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(hash).NotTo(Equal(prev))
+			Expect(svnconfig.PasswordMatches(hash, "changed")).To(BeTrue())
+		})
+
+		It("does not write passwords that svnserve would trim", func() {
+			for _, password := range []string{" s3cret", "s3cret\t", "s3c\nret"} {
+				hash, plaintext, err := factoryWith(password).passwordOf(userWithSecret(svnv1alpha1.PasswordTypePlaintext))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(plaintext).To(BeEmpty(), "password %q", password)
+				Expect(svnconfig.PasswordMatches(hash, password)).To(BeTrue(), "password %q", password)
+			}
+		})
+
+		It("uses hashes as they are", func() {
+			hash, err := svnconfig.HashPassword("s3cret")
+			Expect(err).NotTo(HaveOccurred())
+			got, plaintext, err := factoryWith(hash + "\n").passwordOf(userWithSecret(svnv1alpha1.PasswordTypeHash))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(got).To(Equal(hash))
+			Expect(plaintext).To(BeEmpty())
+
+			By("specifying a plaintext password as a hash")
+			_, _, err = factoryWith("s3cret").passwordOf(userWithSecret(svnv1alpha1.PasswordTypeHash))
+			Expect(err).To(MatchError(ContainSubstring("not a valid hash")))
+		})
+
+		It("rejects missing and empty passwords", func() {
+			f := factoryWith("")
+			_, _, err := f.passwordOf(userWithSecret(svnv1alpha1.PasswordTypePlaintext))
+			Expect(err).To(MatchError(ContainSubstring("is empty")))
+
+			u := userWithSecret(svnv1alpha1.PasswordTypePlaintext)
+			u.Spec.PasswordSecretRef.Name = "unknown"
+			_, _, err = f.passwordOf(u)
+			Expect(err).To(MatchError(ContainSubstring("does not exist")))
+		})
+	})
+
+	Describe("generatePasswords", func() {
+		var u *svnv1alpha1.SVNUser
+		secretKey := types.NamespacedName{Namespace: "default", Name: "noel" + svnv1alpha1.GeneratedPasswordSecretSuffix}
+
+		BeforeEach(func() {
+			u = &svnv1alpha1.SVNUser{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "noel", UID: "noel-uid"},
+				Spec:       svnv1alpha1.SVNUserSpec{SVNServer: "svn"},
+			}
+		})
+
+		generate := func(r *SVNServerReconciler) {
+			users := &svnv1alpha1.SVNUserList{}
+			ExpectWithOffset(1, r.List(ctx, users)).To(Succeed())
+			ExpectWithOffset(1, r.generatePasswords(ctx, logr.Discard(), users)).To(Succeed())
+		}
+		generatedPassword := func(r *SVNServerReconciler) string {
+			secret := &corev1.Secret{}
+			ExpectWithOffset(1, r.Get(ctx, secretKey, secret)).To(Succeed())
+			return string(secret.Data[corev1.BasicAuthPasswordKey])
+		}
+
+		It("generates a password into a basic-auth Secret owned by the SVNUser", func() {
+			r, recorder := newFakeReconciler(u)
+			generate(r)
+
+			secret := &corev1.Secret{}
+			Expect(r.Get(ctx, secretKey, secret)).To(Succeed())
+			Expect(secret.Type).To(Equal(corev1.SecretTypeBasicAuth))
+			Expect(metav1.IsControlledBy(secret, u)).To(BeTrue())
+			Expect(secret.Data).To(HaveKeyWithValue(corev1.BasicAuthUsernameKey, []byte("noel")))
+			Expect(secret.Data[corev1.BasicAuthPasswordKey]).To(HaveLen(GeneratedPasswordLength))
+			Expect(recordedEvents(recorder)).To(ContainElement(ContainSubstring(EventReasonPasswordGenerated)))
+
+			By("generating again")
+			password := generatedPassword(r)
+			generate(r)
+			Expect(generatedPassword(r)).To(Equal(password))
+		})
+
+		It("regenerates the password if the SVNUser is annotated", func() {
+			r, _ := newFakeReconciler(u)
+			generate(r)
+			password := generatedPassword(r)
+
+			Expect(r.Get(ctx, client.ObjectKeyFromObject(u), u)).To(Succeed())
+			u.Annotations = map[string]string{svnv1alpha1.AnnotationRegeneratePassword: ""}
+			Expect(r.Update(ctx, u)).To(Succeed())
+			generate(r)
+			Expect(generatedPassword(r)).NotTo(Equal(password))
+			Expect(r.Get(ctx, client.ObjectKeyFromObject(u), u)).To(Succeed())
+			Expect(u.Annotations).NotTo(HaveKey(svnv1alpha1.AnnotationRegeneratePassword))
+		})
+
+		It("never overwrites Secrets that the SVNUser does not control", func() {
+			u.Annotations = map[string]string{svnv1alpha1.AnnotationRegeneratePassword: ""}
+			secret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Namespace: secretKey.Namespace, Name: secretKey.Name},
+				Data:       map[string][]byte{corev1.BasicAuthPasswordKey: []byte("mine")},
+			}
+			r, _ := newFakeReconciler(u, secret)
+			generate(r)
+			Expect(generatedPassword(r)).To(Equal("mine"))
+		})
+	})
+})
@@ -18,24 +18,43 @@ package controllers
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	stderrors "errors"
+	"fmt"
+	"net"
+	"net/http"
+	"path"
 	"reflect"
 	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-logr/logr"
 	svnv1alpha1 "github.com/markzhang0928/svn-operator/api/v1alpha1"
+	"github.com/markzhang0928/svn-operator/pkg/serverupdater"
 	"github.com/markzhang0928/svn-operator/pkg/svnconfig"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
 )
 
 const (
@@ -43,20 +62,78 @@ const (
 	VolumePathRepos  = "/svn"
 	VolumeNameConfig = "config"
 	VolumePathConfig = "/etc/svn-config/"
+	VolumeNameAuth   = "auth"
+	VolumePathAuth   = "/etc/svn-auth/"
+	VolumeNameTLS    = "tls"
+	VolumePathTLS    = "/etc/svn-tls/"
 
-	ContainerNameSVN = "svn"
+	// VolumeNamePrefixSource is a prefix of volumes that dump files of SVNRepositories are mounted from.
+	VolumeNamePrefixSource = svnv1alpha1.ReservedVolumeNamePrefix
+	VolumePathSources      = "/etc/svn-sources/"
+	// SourceFileName is a file name that dump files in ConfigMaps and Secrets are mounted as.
+	SourceFileName = "dump"
+
+	ContainerNameSVN = svnv1alpha1.ContainerNameSVN
+
+	// EnvTLS and EnvRedirectHTTP are environment variables that make the entrypoint of the SVN server
+	// enable HTTPS and the redirection from HTTP to HTTPS.
+	EnvTLS          = "SVN_TLS"
+	EnvRedirectHTTP = "SVN_REDIRECT_HTTP"
+	// EnvProtocols is a comma-separated list of protocols that the entrypoint of the SVN server starts servers for.
+	// Only Apache is started if it is not set.
+	EnvProtocols = "SVN_PROTOCOLS"
 
 	LabelAppKey          = "app"
 	LabelAppValue        = "subversion"
 	LabelInstanceNameKey = "svn.zhangyi.chat/name"
 
-	ConfigMapKeyAuthUserFile       = "AuthUserFile"
 	ConfigMapKeyAuthzSVNAccessFile = "AuthzSVNAccessFile"
 	ConfigMapKeyRepos              = "Repos"
+	// ConfigMapKeySvnserveConf is a key of the configuration file of svnserve, which exists only if svn:// is served.
+	ConfigMapKeySvnserveConf = "SvnserveConf"
+	// ConfigMapKeyApacheConf is a key of the configuration file of Apache, which is included by apache2.conf.
+	ConfigMapKeyApacheConf = "ApacheConf"
+	// ConfigMapKeyConfigHash and ConfigMapKeyGeneration identify the configuration, so that the server updater
+	// can report which one it has applied.
+	ConfigMapKeyConfigHash = serverupdater.ConfigHashFile
+	ConfigMapKeyGeneration = serverupdater.GenerationFile
+
+	// SecretKeyAuthUserFile is a key of AuthUserFile in the Secret of the SVNServer.
+	// AuthUserFile is kept in a Secret since it contains hashes of passwords.
+	SecretKeyAuthUserFile = "AuthUserFile"
+	// SecretKeySvnservePasswordDB is a key of the password database of svnserve, which exists only if svn:// is served.
+	SecretKeySvnservePasswordDB = "SvnservePasswordDB"
+
+	// SvnservePort is a port that svnserve listens on.
+	SvnservePort = 3690
+
+	IndexKeySVNServer         = ".spec.svnServer"
+	IndexKeyPasswordSecretRef = ".spec.passwordSecretRef.name"
+
+	// ServerUpdaterStatusPort is a port that the server updater serves its status on.
+	ServerUpdaterStatusPort = 8090
+
+	// FinalizerRepositoryRemoval is a finalizer to archive or delete repositories before
+	// SVNRepositories are deleted.
+	FinalizerRepositoryRemoval = "svn.zhangyi.chat/repository-removal"
+
+	// RemovalPollInterval is an interval to check if repositories have been removed.
+	RemovalPollInterval = 10 * time.Second
 
-	IndexKeySVNServer = ".spec.svnServer"
+	// LoadPollInterval is an interval to check progress of loading dump files.
+	LoadPollInterval = 10 * time.Second
 
-	ConditionHistoryLimit = 10
+	// ConfigPollInterval is an interval to check if the pods have applied the latest configuration.
+	ConfigPollInterval = 5 * time.Second
+)
+
+// Here is a list of reasons of events recorded on SVNServers.
+const (
+	EventReasonCreated      = "Created"
+	EventReasonUpdated      = "Updated"
+	EventReasonDeleted      = "Deleted"
+	EventReasonCreateFailed = "CreateFailed"
+	EventReasonUpdateFailed = "UpdateFailed"
 )
 
 // SVNServerReconciler reconciles a SVNServer object
@@ -67,6 +144,14 @@ type SVNServerReconciler struct {
 
 	// DefaultSVNServerImage is a Docker image name to run SVN server.
 	DefaultSVNServerImage string
+
+	// Recorder records events on SVNServers.
+	// If not specified, no events are recorded.
+	Recorder record.EventRecorder
+
+	// HTTPClient is a client to fetch status from server updaters.
+	// If not specified, http.DefaultClient is used.
+	HTTPClient *http.Client
 }
 
 type GeneratorFactory struct {
@@ -74,6 +159,36 @@ type GeneratorFactory struct {
 	repos  *svnv1alpha1.SVNRepositoryList
 	groups *svnv1alpha1.SVNGroupList
 	users  *svnv1alpha1.SVNUserList
+
+	// invalidGroups is a set of SVNGroups that are not written into configuration files
+	// and reasons for them. This is computed by BuildGenerator.
+	invalidGroups map[string]error
+
+	// invalidUsers is a set of SVNUsers that are not written into configuration files
+	// and reasons for them. This is computed by BuildGenerator.
+	invalidUsers map[string]error
+
+	// invalidMemberships is a set of SVNUsers that belong to SVNGroups which do not exist or are invalid,
+	// and reasons for them. They are still written into configuration files without the memberships.
+	// This is computed by BuildGenerator.
+	invalidMemberships map[string]error
+
+	// secrets are Secrets that SVNUsers refer to, keyed by their names.
+	secrets map[string]*corev1.Secret
+
+	// previousHashes are hashes of passwords in the AuthUserFile that has been written, keyed by names of users.
+	// They are reused for plaintext passwords so that the AuthUserFile is not changed on every reconciliation.
+	previousHashes map[string]string
+
+	// hashes are hashes of passwords of valid SVNUsers. This is computed by BuildGenerator.
+	hashes map[string]string
+
+	// passwords are plaintext passwords of valid SVNUsers, which are known only if they are plaintext in Secrets.
+	// This is computed by BuildGenerator.
+	passwords map[string]string
+
+	// ldapBindDN is the DN that Apache binds as to search users in the LDAP directory.
+	ldapBindDN string
 }
 
 // +kubebuilder:rbac:groups=svn.zhangyi.chat,resources=svnservers,verbs=get;list;watch;create;update;patch;delete
@@ -91,6 +206,13 @@ type GeneratorFactory struct {
 // +kubebuilder:rbac:groups=apps,resources=statefulsets,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core,resources=services,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;patch
+// +kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=httproutes,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=roles;rolebindings,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=serviceaccounts,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -110,162 +232,596 @@ func (r *SVNServerReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		if errors.IsNotFound(err) {
 			// The object cloud have been deleted asynchronously.
 			log.Info("SVNServer not found; ignoring.")
-			return ctrl.Result{}, nil
+			if err := r.releaseOrphanedRepositories(ctx, log, req.NamespacedName); err != nil {
+				return ctrl.Result{}, err
+			}
+			return ctrl.Result{}, r.failOrphanedChildren(ctx, log, req.NamespacedName)
 		}
 		log.Error(err, "Failed to get SVNServer")
 		return ctrl.Result{}, err
 	}
-	svc := &corev1.Service{}
-	err = r.Get(ctx, types.NamespacedName{Name: svnServer.Name, Namespace: svnServer.Namespace}, svc)
+
+	result, err := r.reconcileServer(ctx, log, svnServer)
 	if err != nil {
-		if errors.IsNotFound(err) {
-			if err = r.createService(ctx, log, svnServer); err != nil {
-				return ctrl.Result{}, err
-			}
-			return ctrl.Result{Requeue: true}, nil
-		}
+		r.recordFailure(ctx, log, svnServer, err)
+		return ctrl.Result{}, err
 	}
+	return result, nil
+}
+
+// reconcileServer makes the Services, the Ingress or the HTTPRoute, the Secret, the ConfigMap and the StatefulSet of the SVNServer up to date.
+// They are applied on every reconciliation, so changes made by others are reverted.
+// Errors are wrapped by failure so that their reasons are recorded in the status of the SVNServer.
+func (r *SVNServerReconciler) reconcileServer(ctx context.Context, log logr.Logger, svnServer *svnv1alpha1.SVNServer) (ctrl.Result, error) {
+	changed := false
 
-	ss := &appsv1.StatefulSet{}
-	err = r.Get(ctx, types.NamespacedName{Name: svnServer.Name, Namespace: svnServer.Namespace}, ss)
+	desiredSvc, err := r.serviceFor(svnServer)
 	if err != nil {
-		if errors.IsNotFound(err) {
-			if err = r.createStatefulSet(ctx, log, svnServer); err != nil {
-				return ctrl.Result{}, err
-			}
-			return ctrl.Result{Requeue: true}, nil
-		}
-		log.Error(err, "Failed to get StatefulSet")
-		return ctrl.Result{}, err
+		log.Error(err, "Failed to compute desired Service")
+		return ctrl.Result{}, failure(svnv1alpha1.ReasonServiceFailed, err)
+	}
+	svcChanged, err := r.applyOwned(ctx, log, svnServer, desiredSvc, &corev1.Service{})
+	if err != nil {
+		return ctrl.Result{}, failure(svnv1alpha1.ReasonServiceFailed, err)
+	}
+	changed = changed || svcChanged
+
+	desiredClientSvc, err := r.clientServiceFor(svnServer)
+	if err != nil {
+		log.Error(err, "Failed to compute desired client Service")
+		return ctrl.Result{}, failure(svnv1alpha1.ReasonServiceFailed, err)
 	}
+	clientSvcChanged, err := r.applyOwned(ctx, log, svnServer, desiredClientSvc, &corev1.Service{})
+	if err != nil {
+		return ctrl.Result{}, failure(svnv1alpha1.ReasonServiceFailed, err)
+	}
+	changed = changed || clientSvcChanged
+
+	url, ingressChanged, err := r.reconcileIngress(ctx, log, svnServer)
+	if err != nil {
+		return ctrl.Result{}, failure(svnv1alpha1.ReasonIngressFailed, err)
+	}
+	changed = changed || ingressChanged
 
 	repos := &svnv1alpha1.SVNRepositoryList{}
 	err = r.List(ctx, repos, client.InNamespace(svnServer.Namespace), client.MatchingFields{IndexKeySVNServer: svnServer.Name})
 	if err != nil {
 		log.Error(err, "Failed to list SVNRepository")
-		return ctrl.Result{}, err
+		return ctrl.Result{}, failure(svnv1alpha1.ReasonListFailed, err)
+	}
+	if err := r.updateRepositoryFinalizers(ctx, log, repos); err != nil {
+		return ctrl.Result{}, failure(svnv1alpha1.ReasonChildUpdateFailed, err)
 	}
 
 	groups := &svnv1alpha1.SVNGroupList{}
 	err = r.List(ctx, groups, client.InNamespace(svnServer.Namespace), client.MatchingFields{IndexKeySVNServer: svnServer.Name})
 	if err != nil {
 		log.Error(err, "Failed to list SVNGroup")
-		return ctrl.Result{}, err
+		return ctrl.Result{}, failure(svnv1alpha1.ReasonListFailed, err)
 	}
 
 	users := &svnv1alpha1.SVNUserList{}
 	err = r.List(ctx, users, client.InNamespace(svnServer.Namespace), client.MatchingFields{IndexKeySVNServer: svnServer.Name})
 	if err != nil {
 		log.Error(err, "Failed to list SVNUser")
-		return ctrl.Result{}, err
+		return ctrl.Result{}, failure(svnv1alpha1.ReasonListFailed, err)
 	}
 
 	log.Info("reconciling SVNServer")
 
+	// SVNUsers have no passwords if they are authenticated with LDAP.
+	secrets := map[string]*corev1.Secret{}
+	if svnServer.Spec.LDAP() == nil {
+		if err := r.generatePasswords(ctx, log, users); err != nil {
+			return ctrl.Result{}, failure(svnv1alpha1.ReasonSecretFailed, err)
+		}
+		secrets, err = r.passwordSecretsOf(ctx, users)
+		if err != nil {
+			log.Error(err, "Failed to get Secrets of SVNUsers")
+			return ctrl.Result{}, failure(svnv1alpha1.ReasonSecretFailed, err)
+		}
+	}
+	ldapBindDN, err := r.ldapBindDNOf(ctx, svnServer)
+	if err != nil {
+		log.Error(err, "Failed to get the bind DN of LDAP")
+		return ctrl.Result{}, failure(svnv1alpha1.ReasonSecretFailed, err)
+	}
+	// The current AuthUserFile is read so that hashes of plaintext passwords are not computed again.
+	authSecret := &corev1.Secret{}
+	err = r.Get(ctx, types.NamespacedName{Name: svnServer.Name, Namespace: svnServer.Namespace}, authSecret)
+	if err != nil && !errors.IsNotFound(err) {
+		log.Error(err, "Failed to get Secret")
+		return ctrl.Result{}, failure(svnv1alpha1.ReasonSecretFailed, err)
+	}
+
 	factory := &GeneratorFactory{
-		server: svnServer,
-		repos:  repos,
-		groups: groups,
-		users:  users,
+		server:         svnServer,
+		repos:          repos,
+		groups:         groups,
+		users:          users,
+		secrets:        secrets,
+		previousHashes: svnconfig.ParseAuthUserFile(string(authSecret.Data[SecretKeyAuthUserFile])),
+		ldapBindDN:     ldapBindDN,
 	}
-	cm := &corev1.ConfigMap{}
-	err = r.Get(ctx, types.NamespacedName{Name: svnServer.Name, Namespace: svnServer.Namespace}, cm)
+
+	desiredSecret, err := r.authSecretFor(factory)
 	if err != nil {
-		if errors.IsNotFound(err) {
-			if err = r.createConfigMap(ctx, log, factory); err != nil {
-				return ctrl.Result{}, err
-			}
-			return ctrl.Result{Requeue: true}, nil
-		}
+		log.Error(err, "Failed to compute desired Secret")
+		r.recordEvent(svnServer, corev1.EventTypeWarning, EventReasonUpdateFailed, "Failed to compute Secret %s: %v", svnServer.Name, err)
+		return ctrl.Result{}, failure(svnv1alpha1.ReasonSecretFailed, err)
+	}
+	secretChanged, err := r.applyOwned(ctx, log, svnServer, desiredSecret, &corev1.Secret{})
+	if err != nil {
+		return ctrl.Result{}, failure(svnv1alpha1.ReasonSecretFailed, err)
+	}
+	changed = changed || secretChanged
+
+	desiredCM, err := r.configMapFor(factory)
+	if err != nil {
+		log.Error(err, "Failed to compute desired ConfigMap")
+		r.recordEvent(svnServer, corev1.EventTypeWarning, EventReasonUpdateFailed, "Failed to compute ConfigMap %s: %v", svnServer.Name, err)
+		return ctrl.Result{}, failure(svnv1alpha1.ReasonConfigMapFailed, err)
+	}
+	currentCM := &corev1.ConfigMap{}
+	err = r.Get(ctx, types.NamespacedName{Name: svnServer.Name, Namespace: svnServer.Namespace}, currentCM)
+	if err != nil && !errors.IsNotFound(err) {
 		log.Error(err, "Failed to get ConfigMap")
-		return ctrl.Result{}, err
+		return ctrl.Result{}, failure(svnv1alpha1.ReasonConfigMapFailed, err)
+	}
+	configHash := stampConfig(svnServer, currentCM, desiredCM, desiredSecret)
+	cmChanged, err := r.applyOwned(ctx, log, svnServer, desiredCM, &corev1.ConfigMap{})
+	if err != nil {
+		return ctrl.Result{}, failure(svnv1alpha1.ReasonConfigMapFailed, err)
 	}
+	changed = changed || cmChanged
 
-	changed := false
+	rbacChanged, err := r.reconcileConfigSyncRBAC(ctx, log, svnServer)
+	if err != nil {
+		return ctrl.Result{}, failure(svnv1alpha1.ReasonRBACFailed, err)
+	}
+	changed = changed || rbacChanged
 
-	desiredSS := ss.DeepCopy()
-	r.overrideWithPodTemplate(svnServer, desiredSS)
-	if !reflect.DeepEqual(desiredSS, ss) {
-		changed = true
-		if err := r.Update(ctx, desiredSS); err != nil {
-			log.Error(err, "Failed to update StatefulSet")
-			return ctrl.Result{}, err
+	// The StatefulSet is applied last so that the pods start with the configuration above.
+	desiredSS, err := r.statefulSetFor(svnServer)
+	if err != nil {
+		log.Error(err, "Failed to compute desired StatefulSet")
+		r.recordEvent(svnServer, corev1.EventTypeWarning, EventReasonUpdateFailed, "Failed to compute StatefulSet %s: %v", svnServer.Name, err)
+		return ctrl.Result{}, failure(svnv1alpha1.ReasonStatefulSetFailed, err)
+	}
+	mountRepositorySources(repos, desiredSS)
+	ssChanged, err := r.applyOwned(ctx, log, svnServer, desiredSS, &appsv1.StatefulSet{})
+	if err != nil {
+		return ctrl.Result{}, failure(svnv1alpha1.ReasonStatefulSetFailed, err)
+	}
+	changed = changed || ssChanged
+
+	// Nothing is reported as synced until every pod has applied the configuration above.
+	var configConds []metav1.Condition
+	rollingOut := changed || !statefulSetReady(desiredSS)
+	if rollingOut {
+		configConds = readinessConditions(svnv1alpha1.ConditionTypeProgressing, svnv1alpha1.ReasonApplyingConfig, "Waiting for pods to apply the configuration")
+	} else {
+		configConds, err = r.appliedConfigConditions(ctx, log, svnServer, configHash)
+		if err != nil {
+			return ctrl.Result{}, failure(svnv1alpha1.ReasonListFailed, err)
 		}
 	}
 
-	desiredCM, err := r.configMapFor(factory)
+	result, loadsChanged := r.refreshRepositoryLoadStatuses(ctx, log, factory)
+	if err := r.updateChildStatuses(ctx, log, factory, loadsChanged, configConds); err != nil {
+		return ctrl.Result{}, failure(svnv1alpha1.ReasonChildUpdateFailed, err)
+	}
+
+	removalResult, err := r.finalizeRepositories(ctx, log, factory)
 	if err != nil {
-		log.Error(err, "Failed to compute desired configmap")
+		return ctrl.Result{}, failure(svnv1alpha1.ReasonChildUpdateFailed, err)
+	}
+	if removalResult.RequeueAfter > 0 && (result.RequeueAfter == 0 || removalResult.RequeueAfter < result.RequeueAfter) {
+		result.RequeueAfter = removalResult.RequeueAfter
+	}
+
+	conds := readinessConditions(svnv1alpha1.ConditionTypeReady, svnv1alpha1.ReasonAvailable, "All pods are up to date and ready")
+	switch {
+	case rollingOut:
+		conds = readinessConditions(svnv1alpha1.ConditionTypeProgressing, svnv1alpha1.ReasonRollingOut, "Waiting for pods to be up to date and ready")
+	case configConds != nil:
+		conds = configConds
+		// Pods are not watched, so poll them until they have applied the configuration.
+		// Failed pods are polled too, since server updaters retry and may succeed with the same configuration.
+		if result.RequeueAfter == 0 || ConfigPollInterval < result.RequeueAfter {
+			result.RequeueAfter = ConfigPollInterval
+		}
+	}
+	urlChanged := svnServer.Status.URL != url
+	svnServer.Status.URL = url
+	if !setConditions(svnServer, &svnServer.Status.Conditions, &svnServer.Status.ObservedGeneration, conds...) && !urlChanged {
+		return result, nil
+	}
+	if err := r.Status().Update(ctx, svnServer); err != nil {
+		log.Error(err, "Failed to update SVNServer status")
 		return ctrl.Result{}, err
 	}
-	if !reflect.DeepEqual(desiredCM.Data, cm.Data) {
-		changed = true
-		if err := r.Update(ctx, desiredCM); err != nil {
-			log.Error(err, "Failed to update ConfigMap")
-			return ctrl.Result{}, err
+	return result, nil
+}
+
+// reconcileError is an error with the reason why the SVNServer could not be reconciled.
+type reconcileError struct {
+	reason string
+	err    error
+}
+
+func (e *reconcileError) Error() string {
+	return e.err.Error()
+}
+
+func (e *reconcileError) Unwrap() error {
+	return e.err
+}
+
+// failure wraps err with the reason that is recorded in the conditions of the SVNServer.
+func failure(reason string, err error) error {
+	return &reconcileError{reason: reason, err: err}
+}
+
+// recordFailure marks the SVNServer as Degraded with the reason of err.
+// Errors on updating the status are only logged since err is returned anyway.
+func (r *SVNServerReconciler) recordFailure(ctx context.Context, log logr.Logger, s *svnv1alpha1.SVNServer, err error) {
+	reason := svnv1alpha1.ReasonReconcileFailed
+	var rerr *reconcileError
+	if stderrors.As(err, &rerr) {
+		reason = rerr.reason
+	}
+	conds := readinessConditions(svnv1alpha1.ConditionTypeDegraded, reason, err.Error())
+	if !setConditions(s, &s.Status.Conditions, &s.Status.ObservedGeneration, conds...) {
+		return
+	}
+	if err := r.Status().Update(ctx, s); err != nil {
+		log.Error(err, "Failed to update SVNServer status")
+	}
+}
+
+// recordEvent records an event on the SVNServer if the reconciler has an EventRecorder.
+func (r *SVNServerReconciler) recordEvent(s *svnv1alpha1.SVNServer, eventtype, reason, messageFmt string, args ...interface{}) {
+	if r.Recorder == nil {
+		return
+	}
+	r.Recorder.Eventf(s, eventtype, reason, messageFmt, args...)
+}
+
+// statefulSetReady returns true if all replicas of the StatefulSet are up to date and ready.
+func statefulSetReady(ss *appsv1.StatefulSet) bool {
+	replicas := int32(1)
+	if ss.Spec.Replicas != nil {
+		replicas = *ss.Spec.Replicas
+	}
+	return ss.Status.ObservedGeneration >= ss.Generation &&
+		ss.Status.UpdatedReplicas >= replicas &&
+		ss.Status.ReadyReplicas >= replicas
+}
+
+// updateRepositoryFinalizers adds finalizers to SVNRepositories whose actual repositories must be removed
+// on deletion, and removes them from the others.
+func (r *SVNServerReconciler) updateRepositoryFinalizers(ctx context.Context, log logr.Logger, repos *svnv1alpha1.SVNRepositoryList) error {
+	for i := range repos.Items {
+		repo := &repos.Items[i]
+		want := removalPolicyOf(repo) != ""
+		if want == controllerutil.ContainsFinalizer(repo, FinalizerRepositoryRemoval) {
+			continue
+		}
+		if want {
+			if !repo.DeletionTimestamp.IsZero() {
+				// Finalizers cannot be added to objects that are being deleted.
+				continue
+			}
+			controllerutil.AddFinalizer(repo, FinalizerRepositoryRemoval)
+		} else {
+			controllerutil.RemoveFinalizer(repo, FinalizerRepositoryRemoval)
+		}
+		if err := r.Update(ctx, repo); err != nil {
+			log.Error(err, "Failed to update finalizers of SVNRepository", "SVNRepository.Name", repo.Name)
+			return err
 		}
 	}
+	return nil
+}
 
-	if !changed {
+// finalizeRepositories checks if the server updater has removed repositories that are being deleted.
+// Once a repository is removed, the result is recorded in the status of its SVNRepository and
+// then the finalizer is released.
+func (r *SVNServerReconciler) finalizeRepositories(ctx context.Context, log logr.Logger, f *GeneratorFactory) (ctrl.Result, error) {
+	removals := f.BuildRemovals()
+	if len(removals) == 0 {
 		return ctrl.Result{}, nil
 	}
+	status, err := r.fetchServerUpdaterStatus(ctx, f.server)
+	if err != nil {
+		log.Info("Server updater is not available; waiting for repositories to be removed", "error", err.Error())
+		return ctrl.Result{RequeueAfter: RemovalPollInterval}, nil
+	}
 
-	svnServer.Status.Conditions = addCondition(svnServer.Status.Conditions, svnv1alpha1.Condition{
-		Type:           svnv1alpha1.ConditionTypeSynced,
-		Reason:         "successfully synced",
-		TransitionTime: time.Now().Format(time.RFC3339),
-	})
+	pending := false
+	for i := range f.repos.Items {
+		repo := &f.repos.Items[i]
+		if repo.DeletionTimestamp.IsZero() || !controllerutil.ContainsFinalizer(repo, FinalizerRepositoryRemoval) {
+			continue
+		}
+		st := status.Find(repo.Name)
+		if st == nil || st.Exists || st.Error != "" {
+			if st != nil && st.Error != "" {
+				log.Info("Failed to remove repository", "SVNRepository.Name", repo.Name, "error", st.Error)
+			}
+			pending = true
+			continue
+		}
 
-	if err := r.Status().Update(ctx, svnServer); err != nil {
-		log.Error(err, "Failed to update SVNServer status")
-		return ctrl.Result{}, err
+		cond := metav1.Condition{
+			Type:    svnv1alpha1.ConditionTypeDeleted,
+			Status:  metav1.ConditionTrue,
+			Reason:  svnv1alpha1.ReasonRepositoryRemoved,
+			Message: "Repository deleted",
+		}
+		if repo.Spec.DeletionPolicy == svnv1alpha1.DeletionPolicyArchive {
+			cond.Type = svnv1alpha1.ConditionTypeArchived
+			cond.Message = "Repository archived to " + st.ArchivePath
+			if st.ArchivePath == "" {
+				cond.Reason = svnv1alpha1.ReasonRepositoryNotFound
+				cond.Message = "Repository did not exist"
+			}
+		}
+		setConditions(repo, &repo.Status.Conditions, &repo.Status.ObservedGeneration, cond)
+		if err := r.Status().Update(ctx, repo); err != nil {
+			log.Error(err, "Failed to update SVNRepository status", "SVNRepository.Name", repo.Name)
+			return ctrl.Result{}, err
+		}
+		controllerutil.RemoveFinalizer(repo, FinalizerRepositoryRemoval)
+		if err := r.Update(ctx, repo); err != nil {
+			log.Error(err, "Failed to release finalizer of SVNRepository", "SVNRepository.Name", repo.Name)
+			return ctrl.Result{}, err
+		}
+		log.Info("Repository removed", "SVNRepository.Name", repo.Name, "message", cond.Message)
+	}
+	if pending {
+		return ctrl.Result{RequeueAfter: RemovalPollInterval}, nil
 	}
 	return ctrl.Result{}, nil
 }
 
-// Creates a StatefulSet and is corresponding Service
-func (r *SVNServerReconciler) createStatefulSet(ctx context.Context, log logr.Logger, svn *svnv1alpha1.SVNServer) error {
-	ss, err := r.statefulSetFor(svn)
+// refreshRepositoryLoadStatuses fetches progress of loading dump files into the statuses of SVNRepositories.
+// The statuses are not written here but by updateChildStatuses; the names of SVNRepositories whose
+// progress has changed are returned. It keeps polling the server updater until all dump files have
+// been loaded or failed.
+func (r *SVNServerReconciler) refreshRepositoryLoadStatuses(ctx context.Context, log logr.Logger, f *GeneratorFactory) (ctrl.Result, map[string]bool) {
+	var loading []*svnv1alpha1.SVNRepository
+	for i := range f.repos.Items {
+		repo := &f.repos.Items[i]
+		if repo.Spec.Source == nil || !repo.DeletionTimestamp.IsZero() {
+			continue
+		}
+		if loadCompleted(repo) {
+			continue
+		}
+		loading = append(loading, repo)
+	}
+	if len(loading) == 0 {
+		return ctrl.Result{}, nil
+	}
+	status, err := r.fetchServerUpdaterStatus(ctx, f.server)
 	if err != nil {
-		log.Error(err, "Failed to compute desired StatefulSet")
-		return err
+		log.Info("Server updater is not available; waiting for dump files to be loaded", "error", err.Error())
+		return ctrl.Result{RequeueAfter: LoadPollInterval}, nil
+	}
+
+	changed := map[string]bool{}
+	pending := false
+	for _, repo := range loading {
+		st := status.Find(repo.Name)
+		if st == nil || st.Load == nil {
+			// The server updater has not applied the latest configuration yet.
+			pending = true
+			continue
+		}
+		load := &svnv1alpha1.LoadStatus{
+			Phase:    st.Load.Phase,
+			Revision: st.Load.Revision,
+			Message:  st.Load.Error,
+		}
+		if load.Phase == svnv1alpha1.LoadPhaseLoading {
+			pending = true
+		}
+		if !reflect.DeepEqual(load, repo.Status.Load) {
+			repo.Status.Load = load
+			changed[repo.Name] = true
+		}
+	}
+	if pending {
+		return ctrl.Result{RequeueAfter: LoadPollInterval}, changed
 	}
-	log = log.WithValues("StatefulSet.Namespace", ss.Namespace, "StatefulSet.Name", ss.Name)
-	log.Info("Creating a new StatefulSet")
-	if err := r.Create(ctx, ss); err != nil {
-		log.Error(err, "Failed to create new StatefulSet")
+	return ctrl.Result{}, changed
+}
+
+// releaseOrphanedRepositories releases finalizers of SVNRepositories that are being deleted after their
+// SVNServer has gone, since there is no server updater that can remove them anymore.
+func (r *SVNServerReconciler) releaseOrphanedRepositories(ctx context.Context, log logr.Logger, server types.NamespacedName) error {
+	repos := &svnv1alpha1.SVNRepositoryList{}
+	err := r.List(ctx, repos, client.InNamespace(server.Namespace), client.MatchingFields{IndexKeySVNServer: server.Name})
+	if err != nil {
+		log.Error(err, "Failed to list SVNRepository")
 		return err
 	}
+	for i := range repos.Items {
+		repo := &repos.Items[i]
+		if repo.DeletionTimestamp.IsZero() || !controllerutil.ContainsFinalizer(repo, FinalizerRepositoryRemoval) {
+			continue
+		}
+		log.Info("Releasing SVNRepository without removing the repository", "SVNRepository.Name", repo.Name)
+		controllerutil.RemoveFinalizer(repo, FinalizerRepositoryRemoval)
+		if err := r.Update(ctx, repo); err != nil {
+			log.Error(err, "Failed to release finalizer of SVNRepository", "SVNRepository.Name", repo.Name)
+			return err
+		}
+	}
 	return nil
 }
 
-func (r *SVNServerReconciler) createService(ctx context.Context, log logr.Logger, svn *svnv1alpha1.SVNServer) error {
-	svc, err := r.serviceFor(svn)
+// fetchServerUpdaterStatus fetches the status of the server updater running in the SVN server pod.
+func (r *SVNServerReconciler) fetchServerUpdaterStatus(ctx context.Context, s *svnv1alpha1.SVNServer) (*serverupdater.Status, error) {
+	pod := &corev1.Pod{}
+	// The SVN server is a StatefulSet with a single replica.
+	err := r.Get(ctx, types.NamespacedName{Name: s.Name + "-0", Namespace: s.Namespace}, pod)
 	if err != nil {
-		log.Error(err, "Failed to compute desired Service")
-		return err
+		return nil, err
 	}
-	log = log.WithValues("Service.Namespace", svc.Namespace, "Service.Name", svc.Name)
-	log.Info("Creating a new Service")
-	if err := r.Create(ctx, svc); err != nil {
-		log.Error(err, "Failed to create new Service")
-		return err
+	return r.fetchPodStatus(ctx, pod)
+}
+
+// fetchPodStatus fetches the status of the server updater running in the pod.
+func (r *SVNServerReconciler) fetchPodStatus(ctx context.Context, pod *corev1.Pod) (*serverupdater.Status, error) {
+	if pod.Status.PodIP == "" {
+		return nil, fmt.Errorf("pod %s has no IP address", pod.Name)
+	}
+	c := r.HTTPClient
+	if c == nil {
+		c = http.DefaultClient
+	}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	addr := net.JoinHostPort(pod.Status.PodIP, strconv.Itoa(ServerUpdaterStatusPort))
+	return serverupdater.FetchStatus(ctx, c, addr)
+}
+
+// updateChildStatuses marks SVNRepositories, SVNGroups and SVNUsers that are written into configuration
+// files as Ready, and the others as Degraded with the reasons. SVNRepositories whose dump files are being
+// loaded are marked as Progressing. Resources that would be Ready get configConds instead unless it is nil,
+// since the pods have not applied the configuration yet.
+func (r *SVNServerReconciler) updateChildStatuses(ctx context.Context, log logr.Logger, f *GeneratorFactory, loadsChanged map[string]bool, configConds []metav1.Condition) error {
+	for i := range f.repos.Items {
+		repo := &f.repos.Items[i]
+		if !repo.DeletionTimestamp.IsZero() {
+			// The conditions of SVNRepositories being deleted are updated by finalizeRepositories.
+			continue
+		}
+		conds := unlessApplied(repositoryConditionsOf(repo), configConds)
+		changed := setConditions(repo, &repo.Status.Conditions, &repo.Status.ObservedGeneration, conds...)
+		if !changed && !loadsChanged[repo.Name] {
+			continue
+		}
+		if err := r.Status().Update(ctx, repo); err != nil {
+			log.Error(err, "Failed to update SVNRepository status", "SVNRepository.Name", repo.Name)
+			return err
+		}
+	}
+	for i := range f.groups.Items {
+		g := &f.groups.Items[i]
+		conds := unlessApplied(childConditions(svnv1alpha1.ReasonInvalidGroup, f.invalidGroups[g.Name]), configConds)
+		if !setConditions(g, &g.Status.Conditions, &g.Status.ObservedGeneration, conds...) {
+			continue
+		}
+		if err := r.Status().Update(ctx, g); err != nil {
+			log.Error(err, "Failed to update SVNGroup status", "SVNGroup.Name", g.Name)
+			return err
+		}
+	}
+	for i := range f.users.Items {
+		u := &f.users.Items[i]
+		conds := unlessApplied(childConditions(svnv1alpha1.ReasonInvalidGroup, f.userError(u.Name)), configConds)
+		if !setConditions(u, &u.Status.Conditions, &u.Status.ObservedGeneration, conds...) {
+			continue
+		}
+		if err := r.Status().Update(ctx, u); err != nil {
+			log.Error(err, "Failed to update SVNUser status", "SVNUser.Name", u.Name)
+			return err
+		}
 	}
 	return nil
 }
 
-func (r *SVNServerReconciler) createConfigMap(ctx context.Context, log logr.Logger, f *GeneratorFactory) error {
-	cm, err := r.configMapFor(f)
+// childConditions returns conditions of resources that are written into configuration files if err is nil,
+// or those that are not written because of err otherwise. The reason of err is used if err is wrapped
+// by failure, and defaultReason is used otherwise.
+func childConditions(defaultReason string, err error) []metav1.Condition {
 	if err != nil {
-		log.Error(err, "Failed to compute desired ConfigMap")
+		reason := defaultReason
+		var rerr *reconcileError
+		if stderrors.As(err, &rerr) {
+			reason = rerr.reason
+		}
+		return readinessConditions(svnv1alpha1.ConditionTypeDegraded, reason, err.Error())
+	}
+	return readinessConditions(svnv1alpha1.ConditionTypeReady, svnv1alpha1.ReasonSynced, "Written into the configuration of the SVNServer")
+}
+
+// repositoryConditionsOf returns conditions of the SVNRepository taking progress of loading its dump file into account.
+func repositoryConditionsOf(repo *svnv1alpha1.SVNRepository) []metav1.Condition {
+	if repo.Spec.Source == nil {
+		return childConditions("", nil)
+	}
+	load := repo.Status.Load
+	switch {
+	case load == nil:
+		return readinessConditions(svnv1alpha1.ConditionTypeProgressing, svnv1alpha1.ReasonLoading, "Waiting for the dump file to be loaded")
+	case load.Phase == svnv1alpha1.LoadPhaseLoading:
+		return readinessConditions(svnv1alpha1.ConditionTypeProgressing, svnv1alpha1.ReasonLoading,
+			fmt.Sprintf("Loaded %d revisions of the dump file", load.Revision))
+	case load.Phase == svnv1alpha1.LoadPhaseFailed:
+		return readinessConditions(svnv1alpha1.ConditionTypeDegraded, svnv1alpha1.ReasonLoadFailed, load.Message)
+	default:
+		return childConditions("", nil)
+	}
+}
+
+// failOrphanedChildren marks SVNRepositories, SVNGroups and SVNUsers that refer to the SVNServer
+// which does not exist as Degraded.
+func (r *SVNServerReconciler) failOrphanedChildren(ctx context.Context, log logr.Logger, server types.NamespacedName) error {
+	opts := []client.ListOption{client.InNamespace(server.Namespace), client.MatchingFields{IndexKeySVNServer: server.Name}}
+	conds := childConditions(svnv1alpha1.ReasonSVNServerNotFound, fmt.Errorf("SVNServer %q does not exist", server.Name))
+
+	repos := &svnv1alpha1.SVNRepositoryList{}
+	if err := r.List(ctx, repos, opts...); err != nil {
+		log.Error(err, "Failed to list SVNRepository")
+		return err
+	}
+	for i := range repos.Items {
+		repo := &repos.Items[i]
+		if !repo.DeletionTimestamp.IsZero() || !setConditions(repo, &repo.Status.Conditions, &repo.Status.ObservedGeneration, conds...) {
+			continue
+		}
+		if err := r.Status().Update(ctx, repo); err != nil {
+			log.Error(err, "Failed to update SVNRepository status", "SVNRepository.Name", repo.Name)
+			return err
+		}
+	}
+
+	groups := &svnv1alpha1.SVNGroupList{}
+	if err := r.List(ctx, groups, opts...); err != nil {
+		log.Error(err, "Failed to list SVNGroup")
 		return err
 	}
-	log = log.WithValues("ConfigMap.Namespace", cm.Namespace, "ConfigMap.Name", cm.Name)
-	log.Info("Creating a new ConfigMap")
-	if err := r.Create(ctx, cm); err != nil {
-		log.Error(err, "Failed to create new ConfigMap")
+	for i := range groups.Items {
+		g := &groups.Items[i]
+		if !setConditions(g, &g.Status.Conditions, &g.Status.ObservedGeneration, conds...) {
+			continue
+		}
+		if err := r.Status().Update(ctx, g); err != nil {
+			log.Error(err, "Failed to update SVNGroup status", "SVNGroup.Name", g.Name)
+			return err
+		}
+	}
+
+	users := &svnv1alpha1.SVNUserList{}
+	if err := r.List(ctx, users, opts...); err != nil {
+		log.Error(err, "Failed to list SVNUser")
 		return err
 	}
+	for i := range users.Items {
+		u := &users.Items[i]
+		if !setConditions(u, &u.Status.Conditions, &u.Status.ObservedGeneration, conds...) {
+			continue
+		}
+		if err := r.Status().Update(ctx, u); err != nil {
+			log.Error(err, "Failed to update SVNUser status", "SVNUser.Name", u.Name)
+			return err
+		}
+	}
 	return nil
 }
 
@@ -332,13 +888,25 @@ func (r *SVNServerReconciler) overrideWithPodTemplate(s *svnv1alpha1.SVNServer,
 		ss.Spec.Template.Spec.Volumes = append(ss.Spec.Template.Spec.Volumes, *v)
 		volume = &ss.Spec.Template.Spec.Volumes[len(ss.Spec.Template.Spec.Volumes)-1]
 	}
+	// Default values are set explicitly so that the volume does not differ from the one in the cluster.
+	defaultMode := corev1.ConfigMapVolumeSourceDefaultMode
 	volume.VolumeSource = corev1.VolumeSource{
 		ConfigMap: &corev1.ConfigMapVolumeSource{
 			LocalObjectReference: corev1.LocalObjectReference{
 				Name: s.Name,
 			},
+			DefaultMode: &defaultMode,
 		},
 	}
+	setVolume(&ss.Spec.Template.Spec, corev1.Volume{
+		Name: VolumeNameAuth,
+		VolumeSource: corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{
+				SecretName:  s.Name,
+				DefaultMode: &defaultMode,
+			},
+		},
+	})
 
 	var container *corev1.Container
 	for i := range ss.Spec.Template.Spec.Containers {
@@ -352,6 +920,20 @@ func (r *SVNServerReconciler) overrideWithPodTemplate(s *svnv1alpha1.SVNServer,
 		ss.Spec.Template.Spec.Containers = append(ss.Spec.Template.Spec.Containers, r.svnContainerFor(s))
 		container = &ss.Spec.Template.Spec.Containers[len(ss.Spec.Template.Spec.Containers)-1]
 	}
+	setVolumeMount(container, corev1.VolumeMount{
+		Name:      VolumeNameAuth,
+		MountPath: VolumePathAuth,
+		ReadOnly:  true,
+	})
+	if s.Spec.TLS != nil && s.Spec.ServesProtocol(svnv1alpha1.ProtocolHTTP) {
+		configureTLS(s.Spec.TLS, &ss.Spec.Template.Spec, container)
+	}
+	if ldap := s.Spec.LDAP(); ldap != nil {
+		configureLDAP(ldap, &ss.Spec.Template.Spec, container)
+	}
+	if syncsConfigThroughAPI(s) {
+		configureConfigSync(s, &ss.Spec.Template.Spec, container)
+	}
 	if s.Spec.PodTemplate.Image != "" {
 		container.Image = s.Spec.PodTemplate.Image
 	} else {
@@ -379,32 +961,197 @@ func (r *SVNServerReconciler) overrideWithPodTemplate(s *svnv1alpha1.SVNServer,
 		ss.Spec.Template.Spec.Tolerations = make([]corev1.Toleration, len(s.Spec.PodTemplate.Tolerations))
 		copy(ss.Spec.Template.Spec.Tolerations, s.Spec.PodTemplate.Tolerations)
 	}
+	customizePod(&s.Spec.PodTemplate, &ss.Spec.Template, container)
+}
+
+// customizePod applies the resources, environment variables, security contexts, volumes and sidecars
+// of the PodTemplate to the pod template and its SVN container.
+// Volumes, volume mounts, environment variables and containers that the operator has already added
+// take precedence over those with the same names in the PodTemplate.
+func customizePod(t *svnv1alpha1.PodTemplate, template *corev1.PodTemplateSpec, c *corev1.Container) {
+	podSpec := &template.Spec
+	if len(t.Annotations) > 0 {
+		if template.Annotations == nil {
+			template.Annotations = map[string]string{}
+		}
+		for k, v := range t.Annotations {
+			if _, ok := template.Annotations[k]; !ok {
+				template.Annotations[k] = v
+			}
+		}
+	}
+	if len(t.TopologySpreadConstraints) > 0 {
+		podSpec.TopologySpreadConstraints = make([]corev1.TopologySpreadConstraint, len(t.TopologySpreadConstraints))
+		for i := range t.TopologySpreadConstraints {
+			t.TopologySpreadConstraints[i].DeepCopyInto(&podSpec.TopologySpreadConstraints[i])
+		}
+	}
+	if t.PriorityClassName != "" {
+		podSpec.PriorityClassName = t.PriorityClassName
+	}
+	if t.SecurityContext != nil {
+		podSpec.SecurityContext = t.SecurityContext.DeepCopy()
+	}
+	if t.ContainerSecurityContext != nil {
+		c.SecurityContext = t.ContainerSecurityContext.DeepCopy()
+	}
+	t.Resources.DeepCopyInto(&c.Resources)
+
+	envs := map[string]bool{}
+	for _, e := range c.Env {
+		envs[e.Name] = true
+	}
+	for i := range t.Env {
+		if !envs[t.Env[i].Name] {
+			c.Env = append(c.Env, *t.Env[i].DeepCopy())
+		}
+	}
+
+	volumes := map[string]bool{}
+	for _, v := range podSpec.Volumes {
+		volumes[v.Name] = true
+	}
+	for i := range t.Volumes {
+		if !volumes[t.Volumes[i].Name] {
+			podSpec.Volumes = append(podSpec.Volumes, *t.Volumes[i].DeepCopy())
+		}
+	}
+
+	mountPaths := map[string]bool{}
+	for _, m := range c.VolumeMounts {
+		mountPaths[m.MountPath] = true
+	}
+	for i := range t.VolumeMounts {
+		if !mountPaths[t.VolumeMounts[i].MountPath] {
+			c.VolumeMounts = append(c.VolumeMounts, *t.VolumeMounts[i].DeepCopy())
+		}
+	}
+
+	containers := map[string]bool{}
+	for _, sc := range podSpec.Containers {
+		containers[sc.Name] = true
+	}
+	for i := range t.Sidecars {
+		if !containers[t.Sidecars[i].Name] {
+			podSpec.Containers = append(podSpec.Containers, *t.Sidecars[i].DeepCopy())
+		}
+	}
+}
+
+// mountRepositorySources mounts ConfigMaps, Secrets and PersistentVolumeClaims that SVNRepositories
+// are loaded from on the SVN container, and unmounts those that are no longer referred to.
+// Sources of completed loads are unmounted too, but only while no other dump file is being loaded since
+// unmounting them restarts the pods. Those of failed loads are kept, since the load is started again
+// once the source is fixed.
+func mountRepositorySources(repos *svnv1alpha1.SVNRepositoryList, ss *appsv1.StatefulSet) {
+	podSpec := &ss.Spec.Template.Spec
+	volumes := make([]corev1.Volume, 0, len(podSpec.Volumes))
+	for _, v := range podSpec.Volumes {
+		if !strings.HasPrefix(v.Name, VolumeNamePrefixSource) {
+			volumes = append(volumes, v)
+		}
+	}
+	loading := false
+	for i := range repos.Items {
+		repo := &repos.Items[i]
+		load := repo.Status.Load
+		if repo.DeletionTimestamp.IsZero() && repo.Spec.Source != nil && (load == nil || load.Phase == svnv1alpha1.LoadPhaseLoading) {
+			loading = true
+		}
+	}
+	var mounts []corev1.VolumeMount
+	for i := range repos.Items {
+		repo := &repos.Items[i]
+		if !repo.DeletionTimestamp.IsZero() {
+			continue
+		}
+		if !loading && loadCompleted(repo) {
+			continue
+		}
+		v := sourceVolumeOf(repo)
+		if v == nil {
+			continue
+		}
+		volumes = append(volumes, *v)
+		mounts = append(mounts, corev1.VolumeMount{
+			Name:      v.Name,
+			MountPath: VolumePathSources + v.Name,
+			ReadOnly:  true,
+		})
+	}
+	podSpec.Volumes = volumes
+
+	for i := range podSpec.Containers {
+		c := &podSpec.Containers[i]
+		if c.Name != ContainerNameSVN {
+			continue
+		}
+		vms := make([]corev1.VolumeMount, 0, len(c.VolumeMounts)+len(mounts))
+		for _, vm := range c.VolumeMounts {
+			if !strings.HasPrefix(vm.Name, VolumeNamePrefixSource) {
+				vms = append(vms, vm)
+			}
+		}
+		c.VolumeMounts = append(vms, mounts...)
+	}
+}
+
+// loadCompleted returns true if the dump file of the SVNRepository has been loaded.
+func loadCompleted(repo *svnv1alpha1.SVNRepository) bool {
+	return repo.Status.Load != nil && repo.Status.Load.Phase == svnv1alpha1.LoadPhaseCompleted
+}
+
+// sourceVolumeNameOf returns the name of the volume that the dump file of the SVNRepository is mounted from.
+// Names of SVNRepositories are hashed since they can be longer than names of volumes.
+func sourceVolumeNameOf(repo *svnv1alpha1.SVNRepository) string {
+	sum := sha256.Sum256([]byte(repo.Name))
+	return VolumeNamePrefixSource + hex.EncodeToString(sum[:])[:16]
+}
+
+// sourceVolumeOf returns the volume that the dump file of the SVNRepository is mounted from,
+// or nil if the dump file is not in a volume.
+func sourceVolumeOf(repo *svnv1alpha1.SVNRepository) *corev1.Volume {
+	src := repo.Spec.Source
+	if src == nil {
+		return nil
+	}
+	optional := true
+	defaultMode := corev1.ConfigMapVolumeSourceDefaultMode
+	items := func(key string) []corev1.KeyToPath {
+		return []corev1.KeyToPath{{Key: key, Path: SourceFileName}}
+	}
+	v := &corev1.Volume{Name: sourceVolumeNameOf(repo)}
+	switch {
+	case src.PersistentVolumeClaim != nil:
+		v.PersistentVolumeClaim = &corev1.PersistentVolumeClaimVolumeSource{
+			ClaimName: src.PersistentVolumeClaim.ClaimName,
+			ReadOnly:  true,
+		}
+	case src.ConfigMap != nil:
+		// Optional so that the pod can start even after the ConfigMap is deleted.
+		v.ConfigMap = &corev1.ConfigMapVolumeSource{
+			LocalObjectReference: src.ConfigMap.LocalObjectReference,
+			Items:                items(src.ConfigMap.Key),
+			Optional:             &optional,
+			DefaultMode:          &defaultMode,
+		}
+	case src.Secret != nil:
+		v.Secret = &corev1.SecretVolumeSource{
+			SecretName:  src.Secret.Name,
+			Items:       items(src.Secret.Key),
+			Optional:    &optional,
+			DefaultMode: &defaultMode,
+		}
+	default:
+		return nil
+	}
+	return v
 }
 
 func (r *SVNServerReconciler) svnContainerFor(s *svnv1alpha1.SVNServer) corev1.Container {
-	return corev1.Container{
+	c := corev1.Container{
 		Name:  ContainerNameSVN,
 		Image: r.DefaultSVNServerImage,
-		Ports: []corev1.ContainerPort{{
-			ContainerPort: 80,
-			Name:          "http",
-		}},
-		ReadinessProbe: &corev1.Probe{
-			ProbeHandler: corev1.ProbeHandler{
-				HTTPGet: &corev1.HTTPGetAction{
-					Path: "/",
-					Port: intstr.FromInt(80),
-				},
-			},
-		},
-		LivenessProbe: &corev1.Probe{
-			ProbeHandler: corev1.ProbeHandler{
-				HTTPGet: &corev1.HTTPGetAction{
-					Path: "/",
-					Port: intstr.FromInt(80),
-				},
-			},
-		},
 		VolumeMounts: []corev1.VolumeMount{
 			{
 				Name:      VolumeNameRepos,
@@ -414,8 +1161,105 @@ func (r *SVNServerReconciler) svnContainerFor(s *svnv1alpha1.SVNServer) corev1.C
 				Name:      VolumeNameConfig,
 				MountPath: VolumePathConfig,
 			},
+			{
+				Name:      VolumeNameAuth,
+				MountPath: VolumePathAuth,
+				ReadOnly:  true,
+			},
+		},
+	}
+	// Apache is probed if it runs, since svnserve only accepts connections.
+	probe := corev1.ProbeHandler{
+		TCPSocket: &corev1.TCPSocketAction{
+			Port: intstr.FromInt(SvnservePort),
+		},
+	}
+	if s.Spec.ServesProtocol(svnv1alpha1.ProtocolHTTP) {
+		c.Ports = append(c.Ports, corev1.ContainerPort{
+			ContainerPort: 80,
+			Name:          "http",
+			Protocol:      corev1.ProtocolTCP,
+		})
+		probe = corev1.ProbeHandler{
+			HTTPGet: &corev1.HTTPGetAction{
+				Path: "/",
+				Port: intstr.FromInt(80),
+			},
+		}
+	}
+	if s.Spec.ServesProtocol(svnv1alpha1.ProtocolSVN) {
+		c.Ports = append(c.Ports, corev1.ContainerPort{
+			ContainerPort: SvnservePort,
+			Name:          svnv1alpha1.PortNameSVN,
+			Protocol:      corev1.ProtocolTCP,
+		})
+		c.Env = append(c.Env, corev1.EnvVar{Name: EnvProtocols, Value: strings.Join(s.Spec.Protocols, ",")})
+	}
+	c.Ports = append(c.Ports, corev1.ContainerPort{
+		ContainerPort: ServerUpdaterStatusPort,
+		Name:          "updater-status",
+		Protocol:      corev1.ProtocolTCP,
+	})
+	c.ReadinessProbe = &corev1.Probe{ProbeHandler: *probe.DeepCopy()}
+	c.LivenessProbe = &corev1.Probe{ProbeHandler: *probe.DeepCopy()}
+	return c
+}
+
+// configureTLS mounts the TLS Secret into the SVN container, and makes it serve HTTPS on port 443.
+// The probes are moved to HTTPS since HTTP may be redirected.
+func configureTLS(tls *svnv1alpha1.TLSSpec, podSpec *corev1.PodSpec, c *corev1.Container) {
+	defaultMode := corev1.SecretVolumeSourceDefaultMode
+	setVolume(podSpec, corev1.Volume{
+		Name: VolumeNameTLS,
+		VolumeSource: corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{
+				SecretName:  tls.SecretName,
+				DefaultMode: &defaultMode,
+			},
 		},
+	})
+	setVolumeMount(c, corev1.VolumeMount{
+		Name:      VolumeNameTLS,
+		MountPath: VolumePathTLS,
+		ReadOnly:  true,
+	})
+	c.Ports = append(c.Ports, corev1.ContainerPort{
+		ContainerPort: 443,
+		Name:          svnv1alpha1.PortNameHTTPS,
+		Protocol:      corev1.ProtocolTCP,
+	})
+	c.Env = append(c.Env, corev1.EnvVar{Name: EnvTLS, Value: "true"})
+	if tls.RedirectHTTP {
+		c.Env = append(c.Env, corev1.EnvVar{Name: EnvRedirectHTTP, Value: "true"})
+	}
+	for _, probe := range []*corev1.Probe{c.ReadinessProbe, c.LivenessProbe} {
+		if probe != nil && probe.HTTPGet != nil {
+			probe.HTTPGet.Port = intstr.FromInt(443)
+			probe.HTTPGet.Scheme = corev1.URISchemeHTTPS
+		}
+	}
+}
+
+// setVolume adds the volume to the pod, or replaces the volume with the same name.
+func setVolume(podSpec *corev1.PodSpec, volume corev1.Volume) {
+	for i := range podSpec.Volumes {
+		if podSpec.Volumes[i].Name == volume.Name {
+			podSpec.Volumes[i] = volume
+			return
+		}
+	}
+	podSpec.Volumes = append(podSpec.Volumes, volume)
+}
+
+// setVolumeMount adds the volume mount to the container, or replaces the one with the same name.
+func setVolumeMount(c *corev1.Container, mount corev1.VolumeMount) {
+	for i := range c.VolumeMounts {
+		if c.VolumeMounts[i].Name == mount.Name {
+			c.VolumeMounts[i] = mount
+			return
+		}
 	}
+	c.VolumeMounts = append(c.VolumeMounts, mount)
 }
 
 func (r *SVNServerReconciler) serviceFor(s *svnv1alpha1.SVNServer) (*corev1.Service, error) {
@@ -426,14 +1270,18 @@ func (r *SVNServerReconciler) serviceFor(s *svnv1alpha1.SVNServer) (*corev1.Serv
 			Namespace: s.Namespace,
 		},
 		Spec: corev1.ServiceSpec{
-			Ports: []corev1.ServicePort{{
-				Name: "http",
-				Port: 80,
-			}},
 			Selector:  labels,
 			ClusterIP: "None",
 		},
 	}
+	for _, p := range defaultServicePortsOf(s) {
+		svc.Spec.Ports = append(svc.Spec.Ports, corev1.ServicePort{
+			Name:       p.Name,
+			Port:       p.Port,
+			TargetPort: intstr.FromInt(int(p.Port)),
+			Protocol:   corev1.ProtocolTCP,
+		})
+	}
 	err := ctrl.SetControllerReference(s, svc, r.Scheme)
 	if err != nil {
 		return nil, err
@@ -441,17 +1289,79 @@ func (r *SVNServerReconciler) serviceFor(s *svnv1alpha1.SVNServer) (*corev1.Serv
 	return svc, nil
 }
 
+// clientServiceFor returns the client-facing Service of the SVNServer configured by spec.service.
+func (r *SVNServerReconciler) clientServiceFor(s *svnv1alpha1.SVNServer) (*corev1.Service, error) {
+	spec := s.Spec.Service
+	if spec == nil {
+		spec = &svnv1alpha1.ServiceSpec{}
+	}
+	svcType := corev1.ServiceTypeClusterIP
+	if spec.Type != "" {
+		svcType = corev1.ServiceType(spec.Type)
+	}
+	ports := spec.Ports
+	if len(ports) == 0 {
+		ports = defaultServicePortsOf(s)
+	}
+
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        s.Name + svnv1alpha1.ClientServiceSuffix,
+			Namespace:   s.Namespace,
+			Annotations: spec.Annotations,
+		},
+		Spec: corev1.ServiceSpec{
+			Type:     svcType,
+			Selector: r.labelsFor(s),
+		},
+	}
+	for _, p := range ports {
+		svcPort := corev1.ServicePort{
+			Name:       p.Name,
+			Port:       p.Port,
+			TargetPort: intstr.FromString(p.Name),
+			Protocol:   corev1.ProtocolTCP,
+		}
+		if svcType != corev1.ServiceTypeClusterIP {
+			svcPort.NodePort = p.NodePort
+		}
+		svc.Spec.Ports = append(svc.Spec.Ports, svcPort)
+	}
+	if svcType == corev1.ServiceTypeLoadBalancer {
+		svc.Spec.LoadBalancerSourceRanges = spec.LoadBalancerSourceRanges
+	}
+	if err := ctrl.SetControllerReference(s, svc, r.Scheme); err != nil {
+		return nil, err
+	}
+	return svc, nil
+}
+
+// defaultServicePortsOf returns the ports of all protocols that the SVN server serves, on their default port numbers.
+func defaultServicePortsOf(s *svnv1alpha1.SVNServer) []svnv1alpha1.ServicePort {
+	var ports []svnv1alpha1.ServicePort
+	if s.Spec.ServesProtocol(svnv1alpha1.ProtocolHTTP) {
+		ports = append(ports, svnv1alpha1.ServicePort{Name: svnv1alpha1.PortNameHTTP, Port: 80})
+		if s.Spec.TLS != nil {
+			ports = append(ports, svnv1alpha1.ServicePort{Name: svnv1alpha1.PortNameHTTPS, Port: 443})
+		}
+	}
+	if s.Spec.ServesProtocol(svnv1alpha1.ProtocolSVN) {
+		ports = append(ports, svnv1alpha1.ServicePort{Name: svnv1alpha1.PortNameSVN, Port: SvnservePort})
+	}
+	return ports
+}
+
 func (r *SVNServerReconciler) configMapFor(f *GeneratorFactory) (*corev1.ConfigMap, error) {
 	gen := f.BuildGenerator()
-	authUserFile, err := gen.AuthUserFile()
+	authzSVNAccessFile, err := gen.AuthzSVNAccessFile()
 	if err != nil {
 		return nil, err
 	}
-	authzSVNAccessFile, err := gen.AuthzSVNAccessFile()
+	reposConfig, err := gen.ReposConfig()
 	if err != nil {
 		return nil, err
 	}
-	reposConfig, err := gen.ReposConfig()
+	apacheConf, err := gen.ApacheConf()
 	if err != nil {
 		return nil, err
 	}
@@ -461,11 +1371,21 @@ func (r *SVNServerReconciler) configMapFor(f *GeneratorFactory) (*corev1.ConfigM
 			Namespace: f.server.Namespace,
 		},
 		Data: map[string]string{
-			ConfigMapKeyAuthUserFile:       authUserFile,
 			ConfigMapKeyAuthzSVNAccessFile: authzSVNAccessFile,
 			ConfigMapKeyRepos:              reposConfig,
+			ConfigMapKeyApacheConf:         apacheConf,
 		},
 	}
+	if f.server.Spec.ServesProtocol(svnv1alpha1.ProtocolSVN) {
+		svnserveConf, err := gen.SvnserveConf(
+			path.Join(VolumePathAuth, SecretKeySvnservePasswordDB),
+			path.Join(VolumePathConfig, ConfigMapKeyAuthzSVNAccessFile),
+		)
+		if err != nil {
+			return nil, err
+		}
+		cm.Data[ConfigMapKeySvnserveConf] = svnserveConf
+	}
 	err = ctrl.SetControllerReference(f.server, cm, r.Scheme)
 	if err != nil {
 		return nil, err
@@ -473,6 +1393,35 @@ func (r *SVNServerReconciler) configMapFor(f *GeneratorFactory) (*corev1.ConfigM
 	return cm, nil
 }
 
+func (r *SVNServerReconciler) authSecretFor(f *GeneratorFactory) (*corev1.Secret, error) {
+	gen := f.BuildGenerator()
+	authUserFile, err := gen.AuthUserFile()
+	if err != nil {
+		return nil, err
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      f.server.Name,
+			Namespace: f.server.Namespace,
+		},
+		Data: map[string][]byte{
+			SecretKeyAuthUserFile: []byte(authUserFile),
+		},
+	}
+	if f.server.Spec.ServesProtocol(svnv1alpha1.ProtocolSVN) {
+		passwordDB, err := gen.SvnservePasswordDB()
+		if err != nil {
+			return nil, err
+		}
+		secret.Data[SecretKeySvnservePasswordDB] = []byte(passwordDB)
+	}
+	err = ctrl.SetControllerReference(f.server, secret, r.Scheme)
+	if err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
+
 func (r *SVNServerReconciler) labelsFor(s *svnv1alpha1.SVNServer) map[string]string {
 	return map[string]string{
 		LabelAppKey:          LabelAppValue,
@@ -480,62 +1429,222 @@ func (r *SVNServerReconciler) labelsFor(s *svnv1alpha1.SVNServer) map[string]str
 	}
 }
 
-func addCondition(conds []svnv1alpha1.Condition, newCond svnv1alpha1.Condition) []svnv1alpha1.Condition {
-	conds = append(conds, newCond)
-	l := len(conds)
-	if l <= ConditionHistoryLimit {
-		return conds
-	}
-	sort.Slice(conds, func(ix, iy int) bool {
-		// Parsing times is relatively heavy, but we can ignore this cost because
-		// the number of elements in conds is very small.
-		tx, ex := time.Parse(time.RFC3339, conds[ix].TransitionTime)
-		ty, ey := time.Parse(time.RFC3339, conds[iy].TransitionTime)
-		// We consider invalid times as earlier than any valid times.
-		if ex != nil && ey != nil {
-			return false
-		} else if ex != nil {
-			return true
-		} else if ey != nil {
-			return false
-		}
-		return tx.Before(ty)
-	})
-
-	// l-ConditionHistoryLimit > 0 is guaranteed by the above condition.
-	return conds[l-ConditionHistoryLimit : l]
-}
-
 func (f *GeneratorFactory) BuildGenerator() *svnconfig.Generator {
-	repos := f.BuildRepositories()
+	f.sortItems()
 	groups := f.BuildGroups()
+	repos := f.BuildRepositories()
 	users := f.BuildUsers()
+	removals := f.BuildRemovals()
+	apache := svnconfig.Apache{
+		ServerName:         serverNameOf(f.server),
+		Location:           f.server.Spec.URLPrefix(),
+		ParentPath:         path.Join(VolumePathRepos, "repos"),
+		AuthUserFile:       path.Join(VolumePathAuth, SecretKeyAuthUserFile),
+		AuthzSVNAccessFile: path.Join(VolumePathConfig, ConfigMapKeyAuthzSVNAccessFile),
+	}
+	if tuning := f.server.Spec.Apache; tuning != nil {
+		apache.Timeout = tuning.TimeoutSeconds
+		apache.KeepAlive = tuning.KeepAlive
+		apache.KeepAliveTimeout = tuning.KeepAliveTimeoutSeconds
+		apache.ListParentPath = tuning.ListParentPath
+		apache.CompressionLevel = tuning.CompressionLevel
+		if q := tuning.InMemoryCacheSize; q != nil {
+			// mod_dav_svn counts kilobytes in units of 1024 bytes.
+			apache.InMemoryCacheSizeKB = ptr.To(q.Value() / 1024)
+		}
+		if q := tuning.LimitXMLRequestBody; q != nil {
+			apache.LimitXMLRequestBody = ptr.To(q.Value())
+		}
+	}
+	if ldap := f.server.Spec.LDAP(); ldap != nil {
+		apache.LDAP = apacheLDAPOf(ldap, f.ldapBindDN)
+	}
 	return &svnconfig.Generator{
 		Repositories: repos,
 		Groups:       groups,
 		Users:        users,
+		Removals:     removals,
+		Apache:       apache,
 	}
 }
+
+// serverNameOf returns the host name that Apache of the SVNServer identifies itself with.
+// It defaults to the host that the repositories are exposed at, or the DNS name of the client-facing Service.
+func serverNameOf(s *svnv1alpha1.SVNServer) string {
+	switch {
+	case s.Spec.Apache != nil && s.Spec.Apache.ServerName != "":
+		return s.Spec.Apache.ServerName
+	case s.Spec.Ingress != nil:
+		return s.Spec.Ingress.Host
+	case s.Spec.HTTPRoute != nil:
+		return s.Spec.HTTPRoute.Host
+	default:
+		return s.Name + svnv1alpha1.ClientServiceSuffix + "." + s.Namespace + ".svc"
+	}
+}
+
+// sortItems sorts all resources by their names so that generated files do not depend on
+// the order in which they are listed.
+func (f *GeneratorFactory) sortItems() {
+	sort.Slice(f.repos.Items, func(i, j int) bool {
+		return f.repos.Items[i].Name < f.repos.Items[j].Name
+	})
+	sort.Slice(f.groups.Items, func(i, j int) bool {
+		return f.groups.Items[i].Name < f.groups.Items[j].Name
+	})
+	sort.Slice(f.users.Items, func(i, j int) bool {
+		return f.users.Items[i].Name < f.users.Items[j].Name
+	})
+}
+
 func (f *GeneratorFactory) BuildRepositories() []svnconfig.Repository {
 	repos := make([]svnconfig.Repository, 0, len(f.repos.Items))
 	for i := range f.repos.Items {
 		r := f.repos.Items[i]
+		if !r.DeletionTimestamp.IsZero() {
+			continue
+		}
 		perms := f.buildPermissionsOf(r.Name)
-		repos = append(repos, svnconfig.Repository{Name: r.Name, Permissions: perms})
+		repos = append(repos, svnconfig.Repository{
+			Name:          r.Name,
+			Permissions:   perms,
+			Access:        r.Spec.Access,
+			InitialLayout: initialLayoutOf(&r),
+			Source:        sourceOf(&r),
+			HTTP:          repositoryHTTPOf(&r),
+		})
 	}
 	return repos
 }
 
+// repositoryHTTPOf converts the HTTP settings of the SVNRepository into the configuration of Apache.
+func repositoryHTTPOf(r *svnv1alpha1.SVNRepository) *svnconfig.RepositoryHTTP {
+	h := r.Spec.HTTP
+	if h == nil {
+		return nil
+	}
+	conf := &svnconfig.RepositoryHTTP{
+		Autoversioning:      h.Autoversioning,
+		IndexXSLT:           h.IndexXSLT,
+		AllowedSourceRanges: h.AllowedSourceRanges,
+	}
+	if q := h.LimitRequestBody; q != nil {
+		conf.LimitRequestBody = ptr.To(q.Value())
+	}
+	return conf
+}
+
+// standardLayout is a list of directories of InitialLayoutPresetStandard.
+var standardLayout = []string{"trunk", "branches", "tags"}
+
+// initialLayoutOf returns directories of the preset followed by those specified explicitly.
+func initialLayoutOf(r *svnv1alpha1.SVNRepository) []string {
+	l := r.Spec.InitialLayout
+	if l == nil {
+		return nil
+	}
+	dirs := make([]string, 0, len(standardLayout)+len(l.Directories))
+	if l.Preset == svnv1alpha1.InitialLayoutPresetStandard {
+		dirs = append(dirs, standardLayout...)
+	}
+	for _, d := range l.Directories {
+		if !svnconfig.IsValidLayoutDirectory(d) {
+			continue
+		}
+		found := false
+		for _, e := range dirs {
+			if d == e {
+				found = true
+				break
+			}
+		}
+		if !found {
+			dirs = append(dirs, d)
+		}
+	}
+	return dirs
+}
+
+// sourceOf returns the location of the dump file of the SVNRepository in the SVN server.
+func sourceOf(r *svnv1alpha1.SVNRepository) *svnconfig.Source {
+	src := r.Spec.Source
+	if src == nil {
+		return nil
+	}
+	if src.URL != "" {
+		return &svnconfig.Source{URL: src.URL}
+	}
+	if sourceVolumeOf(r) == nil {
+		return nil
+	}
+	file := SourceFileName
+	if src.PersistentVolumeClaim != nil {
+		// Cleaning the path as an absolute path prevents it from escaping from the volume.
+		file = path.Clean("/" + src.PersistentVolumeClaim.Path)[1:]
+	}
+	return &svnconfig.Source{File: VolumePathSources + sourceVolumeNameOf(r) + "/" + file}
+}
+
+// BuildRemovals builds removals of repositories whose SVNRepositories are being deleted
+// and are waiting for the repositories to be archived or deleted.
+func (f *GeneratorFactory) BuildRemovals() []svnconfig.Removal {
+	removals := make([]svnconfig.Removal, 0)
+	for i := range f.repos.Items {
+		r := &f.repos.Items[i]
+		if r.DeletionTimestamp.IsZero() || !controllerutil.ContainsFinalizer(r, FinalizerRepositoryRemoval) {
+			continue
+		}
+		if policy := removalPolicyOf(r); policy != "" {
+			removals = append(removals, svnconfig.Removal{Name: r.Name, Policy: policy})
+		}
+	}
+	return removals
+}
+
+// removalPolicyOf returns how the repository is removed on deletion, or an empty string
+// if the repository is retained.
+func removalPolicyOf(r *svnv1alpha1.SVNRepository) string {
+	switch r.Spec.DeletionPolicy {
+	case svnv1alpha1.DeletionPolicyArchive:
+		return svnconfig.RemovalArchive
+	case svnv1alpha1.DeletionPolicyDelete:
+		return svnconfig.RemovalDelete
+	default:
+		return ""
+	}
+}
+
+// buildPermissionsOf builds permissions of groups followed by those granted to users directly.
 func (f *GeneratorFactory) buildPermissionsOf(repoName string) []svnconfig.Permission {
 	perms := make([]svnconfig.Permission, 0, len(f.groups.Items))
 	for i := range f.groups.Items {
 		g := &f.groups.Items[i]
+		if _, ok := f.invalidGroups[g.Name]; ok {
+			continue
+		}
 		for j := range g.Spec.Permissions {
 			p := g.Spec.Permissions[j]
 			if repoName == p.Repository {
 				perms = append(perms, svnconfig.Permission{
 					Group:      g.Name,
 					Permission: p.Permission,
+					Path:       svnconfig.NormalizePath(p.Path),
+				})
+			}
+		}
+	}
+	for i := range f.users.Items {
+		u := &f.users.Items[i]
+		if _, ok := f.invalidUsers[u.Name]; ok {
+			continue
+		}
+		for j := range u.Spec.Permissions {
+			p := u.Spec.Permissions[j]
+			if repoName == p.Repository {
+				perms = append(perms, svnconfig.Permission{
+					User:       u.Name,
+					Permission: p.Permission,
+					Path:       svnconfig.NormalizePath(p.Path),
 				})
 			}
 		}
@@ -543,6 +1652,7 @@ func (f *GeneratorFactory) buildPermissionsOf(repoName string) []svnconfig.Permi
 	return perms
 }
 
+// BuildGroups builds groups except for invalid ones, which are recorded in f.invalidGroups.
 func (f *GeneratorFactory) BuildGroups() []svnconfig.Group {
 	groups := make([]svnconfig.Group, 0, len(f.groups.Items))
 	for i := range f.groups.Items {
@@ -557,21 +1667,48 @@ func (f *GeneratorFactory) BuildGroups() []svnconfig.Group {
 				}
 			}
 		}
+		subgroups := make([]string, 0, len(g.Spec.Groups))
+		for j := range g.Spec.Groups {
+			subgroups = append(subgroups, g.Spec.Groups[j].Name)
+		}
 		groups = append(groups, svnconfig.Group{
-			Name:  g.Name,
-			Users: users,
+			Name:   g.Name,
+			Users:  users,
+			Groups: subgroups,
 		})
 	}
-	return groups
+
+	f.invalidGroups = svnconfig.InvalidGroups(groups)
+	f.resolveUsers()
+	valid := make([]svnconfig.Group, 0, len(groups))
+	for i := range groups {
+		if _, ok := f.invalidGroups[groups[i].Name]; ok {
+			continue
+		}
+		g := groups[i]
+		users := make([]string, 0, len(g.Users))
+		for _, u := range g.Users {
+			if _, ok := f.invalidUsers[u]; !ok {
+				users = append(users, u)
+			}
+		}
+		g.Users = users
+		valid = append(valid, g)
+	}
+	return valid
 }
 
 func (f *GeneratorFactory) BuildUsers() []svnconfig.User {
 	users := make([]svnconfig.User, 0, len(f.users.Items))
 	for i := range f.users.Items {
 		u := &f.users.Items[i]
+		if _, ok := f.invalidUsers[u.Name]; ok {
+			continue
+		}
 		users = append(users, svnconfig.User{
 			Name:              u.Name,
-			EncryptedPassword: u.Spec.EncryptedPassword,
+			EncryptedPassword: f.hashes[u.Name],
+			Password:          f.passwords[u.Name],
 		})
 	}
 	return users
@@ -597,14 +1734,48 @@ func (r *SVNServerReconciler) SetupWithManager(ctx context.Context, mgr ctrl.Man
 	}); err != nil {
 		return err
 	}
-	return ctrl.NewControllerManagedBy(mgr).
+	if err := mgr.GetFieldIndexer().IndexField(ctx, &svnv1alpha1.SVNUser{}, IndexKeyPasswordSecretRef, func(rawObj client.Object) []string {
+		ref := passwordSecretRefOf(rawObj.(*svnv1alpha1.SVNUser))
+		if ref == nil {
+			return nil
+		}
+		return []string{ref.Name}
+	}); err != nil {
+		return err
+	}
+	if err := mgr.GetFieldIndexer().IndexField(ctx, &svnv1alpha1.SVNServer{}, IndexKeyLDAPSecretName, func(rawObj client.Object) []string {
+		return ldapSecretNamesOf(rawObj.(*svnv1alpha1.SVNServer))
+	}); err != nil {
+		return err
+	}
+	// Only metadata of Secrets is watched, so that the operator does not keep every Secret in the cluster
+	// in memory. Their data is read from the API server on demand since the client does not cache Secrets.
+	b := ctrl.NewControllerManagedBy(mgr).
 		For(&svnv1alpha1.SVNServer{}).
 		Watches(&svnv1alpha1.SVNRepository{}, handler.EnqueueRequestsFromMapFunc(repositoryEnqueuer(mgr))).
 		Watches(&svnv1alpha1.SVNGroup{}, handler.EnqueueRequestsFromMapFunc(groupEnqueuer(mgr))).
 		Watches(&svnv1alpha1.SVNUser{}, handler.EnqueueRequestsFromMapFunc(userEnqueuer(mgr))).
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(passwordSecretEnqueuer(mgr)), builder.OnlyMetadata).
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(ldapSecretEnqueuer(mgr)), builder.OnlyMetadata).
+		Owns(&corev1.Service{}).
 		Owns(&appsv1.StatefulSet{}).
 		Owns(&corev1.ConfigMap{}).
-		Complete(r)
+		Owns(&corev1.Secret{}, builder.OnlyMetadata).
+		Owns(&corev1.ServiceAccount{}).
+		Owns(&rbacv1.Role{}).
+		Owns(&rbacv1.RoleBinding{}).
+		Owns(&networkingv1.Ingress{})
+	// HTTPRoutes are watched only if the Gateway API is installed, so that the operator runs without it.
+	_, err := mgr.GetRESTMapper().RESTMapping(schema.GroupKind{Group: gatewayv1.GroupName, Kind: "HTTPRoute"}, gatewayv1.GroupVersion.Version)
+	switch {
+	case err == nil:
+		b = b.Owns(&gatewayv1.HTTPRoute{})
+	case meta.IsNoMatchError(err):
+		r.Log.Info("Gateway API is not installed; HTTPRoutes are not watched")
+	default:
+		return err
+	}
+	return b.Complete(r)
 }
 
 func repositoryEnqueuer(mgr ctrl.Manager) handler.MapFunc {
@@ -17,17 +17,32 @@ limitations under the License.
 package controllers
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	svnv1alpha1 "github.com/markzhang0928/svn-operator/api/v1alpha1"
+	"github.com/markzhang0928/svn-operator/pkg/serverupdater"
 )
 
 var _ = Describe("SVNServer Controller", func() {
@@ -51,7 +66,17 @@ var _ = Describe("SVNServer Controller", func() {
 						Name:      resourceName,
 						Namespace: "default",
 					},
-					// TODO(user): Specify other spec details if needed.
+					Spec: svnv1alpha1.SVNServerSpec{
+						PodTemplate: svnv1alpha1.PodTemplate{Image: "svn-server:latest"},
+						VolumeClaimTemplate: corev1.PersistentVolumeClaim{
+							Spec: corev1.PersistentVolumeClaimSpec{
+								AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+								Resources: corev1.VolumeResourceRequirements{
+									Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("1Gi")},
+								},
+							},
+						},
+					},
 				}
 				Expect(k8sClient.Create(ctx, resource)).To(Succeed())
 			}
@@ -80,5 +105,649 @@ var _ = Describe("SVNServer Controller", func() {
 			// TODO(user): Add more specific assertions depending on your controller's reconciliation logic.
 			// Example: If you expect a certain status condition after reconciliation, verify it here.
 		})
+
+		It("should revert changes to the owned objects", func() {
+			controllerReconciler := &SVNServerReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+			reconcileServer := func() {
+				_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+				ExpectWithOffset(1, err).NotTo(HaveOccurred())
+			}
+			reconcileServer()
+
+			By("checking the client-facing Service")
+			clientSvc := &corev1.Service{}
+			clientName := types.NamespacedName{Namespace: "default", Name: resourceName + svnv1alpha1.ClientServiceSuffix}
+			Expect(k8sClient.Get(ctx, clientName, clientSvc)).To(Succeed())
+			Expect(clientSvc.Spec.Type).To(Equal(corev1.ServiceTypeClusterIP))
+			Expect(clientSvc.Spec.Ports).To(HaveLen(1))
+
+			By("changing the Service")
+			svc := &corev1.Service{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, svc)).To(Succeed())
+			svc.Spec.Ports[0].Port = 8080
+			Expect(k8sClient.Update(ctx, svc)).To(Succeed())
+			reconcileServer()
+			Expect(k8sClient.Get(ctx, typeNamespacedName, svc)).To(Succeed())
+			Expect(svc.Spec.Ports[0].Port).To(BeEquivalentTo(80))
+
+			By("deleting the Service")
+			Expect(k8sClient.Delete(ctx, svc)).To(Succeed())
+			reconcileServer()
+			Expect(k8sClient.Get(ctx, typeNamespacedName, &corev1.Service{})).To(Succeed())
+
+			By("changing the ConfigMap")
+			cm := &corev1.ConfigMap{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, cm)).To(Succeed())
+			cm.Data[ConfigMapKeyAuthzSVNAccessFile] = "[/]\n* = rw\n"
+			Expect(k8sClient.Update(ctx, cm)).To(Succeed())
+			reconcileServer()
+			Expect(k8sClient.Get(ctx, typeNamespacedName, cm)).To(Succeed())
+			Expect(cm.Data[ConfigMapKeyAuthzSVNAccessFile]).NotTo(ContainSubstring("* = rw"))
+		})
+
+		It("should serve HTTPS if spec.tls is specified", func() {
+			controllerReconciler := &SVNServerReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+			s := &svnv1alpha1.SVNServer{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, s)).To(Succeed())
+			s.Spec.TLS = &svnv1alpha1.TLSSpec{SecretName: "svn-tls", RedirectHTTP: true}
+			Expect(k8sClient.Update(ctx, s)).To(Succeed())
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			ss := &appsv1.StatefulSet{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, ss)).To(Succeed())
+			c := ss.Spec.Template.Spec.Containers[0]
+			Expect(c.Ports).To(ContainElement(HaveField("Name", svnv1alpha1.PortNameHTTPS)))
+			Expect(c.Env).To(ContainElements(
+				corev1.EnvVar{Name: EnvTLS, Value: "true"},
+				corev1.EnvVar{Name: EnvRedirectHTTP, Value: "true"},
+			))
+			Expect(c.ReadinessProbe.HTTPGet.Scheme).To(Equal(corev1.URISchemeHTTPS))
+			Expect(c.VolumeMounts).To(ContainElement(HaveField("MountPath", VolumePathTLS)))
+
+			clientSvc := &corev1.Service{}
+			clientName := types.NamespacedName{Namespace: "default", Name: resourceName + svnv1alpha1.ClientServiceSuffix}
+			Expect(k8sClient.Get(ctx, clientName, clientSvc)).To(Succeed())
+			Expect(clientSvc.Spec.Ports).To(ContainElement(HaveField("Port", BeEquivalentTo(443))))
+		})
+
+		It("should serve svn:// if spec.protocols contains svn", func() {
+			controllerReconciler := &SVNServerReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+			s := &svnv1alpha1.SVNServer{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, s)).To(Succeed())
+			s.Spec.Protocols = []string{svnv1alpha1.ProtocolHTTP, svnv1alpha1.ProtocolSVN}
+			Expect(k8sClient.Update(ctx, s)).To(Succeed())
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			ss := &appsv1.StatefulSet{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, ss)).To(Succeed())
+			c := ss.Spec.Template.Spec.Containers[0]
+			Expect(c.Ports).To(ContainElement(HaveField("Name", svnv1alpha1.PortNameSVN)))
+			Expect(c.Env).To(ContainElement(corev1.EnvVar{Name: EnvProtocols, Value: "http,svn"}))
+
+			cm := &corev1.ConfigMap{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, cm)).To(Succeed())
+			Expect(cm.Data).To(HaveKey(ConfigMapKeySvnserveConf))
+			secret := &corev1.Secret{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, secret)).To(Succeed())
+			Expect(secret.Data).To(HaveKey(SecretKeySvnservePasswordDB))
+
+			clientSvc := &corev1.Service{}
+			clientName := types.NamespacedName{Namespace: "default", Name: resourceName + svnv1alpha1.ClientServiceSuffix}
+			Expect(k8sClient.Get(ctx, clientName, clientSvc)).To(Succeed())
+			Expect(clientSvc.Spec.Ports).To(ContainElement(HaveField("Port", BeEquivalentTo(SvnservePort))))
+		})
+
+		It("should stamp the configuration with its hash and generation", func() {
+			controllerReconciler := &SVNServerReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			s := &svnv1alpha1.SVNServer{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, s)).To(Succeed())
+			cm := &corev1.ConfigMap{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, cm)).To(Succeed())
+			secret := &corev1.Secret{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, secret)).To(Succeed())
+			Expect(cm.Data).To(HaveKeyWithValue(ConfigMapKeyGeneration, strconv.FormatInt(s.Generation, 10)))
+			Expect(cm.Data).To(HaveKeyWithValue(ConfigMapKeyConfigHash, serverupdater.HashConfig(cm.Data, secret.Data)))
+
+			By("checking that the SVNServer is not ready until the pods apply the configuration")
+			Expect(meta.IsStatusConditionTrue(s.Status.Conditions, svnv1alpha1.ConditionTypeReady)).To(BeFalse())
+		})
+
+		It("should authenticate users with LDAP if spec.authentication.mode is LDAP", func() {
+			controllerReconciler := &SVNServerReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+			bind := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "ldap-bind"},
+				StringData: map[string]string{
+					svnv1alpha1.LDAPSecretKeyBindDN:       "cn=admin,dc=example,dc=org",
+					svnv1alpha1.LDAPSecretKeyBindPassword: "admin",
+				},
+			}
+			Expect(k8sClient.Create(ctx, bind)).To(Succeed())
+			DeferCleanup(func() { Expect(k8sClient.Delete(ctx, bind)).To(Succeed()) })
+
+			s := &svnv1alpha1.SVNServer{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, s)).To(Succeed())
+			s.Spec.Authentication = &svnv1alpha1.AuthenticationSpec{
+				Mode: svnv1alpha1.AuthenticationModeLDAP,
+				LDAP: &svnv1alpha1.LDAPSpec{
+					URL:            "ldap://openldap/ou=people,dc=example,dc=org",
+					BindSecretName: "ldap-bind",
+				},
+			}
+			Expect(k8sClient.Update(ctx, s)).To(Succeed())
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			cm := &corev1.ConfigMap{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, cm)).To(Succeed())
+			Expect(cm.Data[ConfigMapKeyApacheConf]).To(And(
+				ContainSubstring(`AuthLDAPURL "ldap://openldap/ou=people,dc=example,dc=org?uid?sub?"`),
+				ContainSubstring(`AuthLDAPBindDN "cn=admin,dc=example,dc=org"`),
+				Not(ContainSubstring("AuthUserFile")),
+			))
+
+			ss := &appsv1.StatefulSet{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, ss)).To(Succeed())
+			Expect(ss.Spec.Template.Spec.Containers[0].VolumeMounts).To(ContainElement(HaveField("MountPath", VolumePathLDAPBind)))
+		})
+
+		It("should customize the pods with spec.podTemplate", func() {
+			controllerReconciler := &SVNServerReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+			s := &svnv1alpha1.SVNServer{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, s)).To(Succeed())
+			s.Spec.PodTemplate.Resources = corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("256Mi")},
+			}
+			s.Spec.PodTemplate.Env = []corev1.EnvVar{{Name: "TZ", Value: "Asia/Tokyo"}}
+			s.Spec.PodTemplate.Volumes = []corev1.Volume{{
+				Name:         "logs",
+				VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+			}}
+			s.Spec.PodTemplate.VolumeMounts = []corev1.VolumeMount{{Name: "logs", MountPath: "/var/log/apache2"}}
+			s.Spec.PodTemplate.Sidecars = []corev1.Container{{
+				Name:         "log-shipper",
+				Image:        "fluent/fluent-bit",
+				VolumeMounts: []corev1.VolumeMount{{Name: "logs", MountPath: "/logs", ReadOnly: true}},
+			}}
+			Expect(k8sClient.Update(ctx, s)).To(Succeed())
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			ss := &appsv1.StatefulSet{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, ss)).To(Succeed())
+			podSpec := ss.Spec.Template.Spec
+			Expect(podSpec.Containers).To(HaveLen(2))
+			Expect(podSpec.Containers[1].Name).To(Equal("log-shipper"))
+			Expect(podSpec.Volumes).To(ContainElements(
+				HaveField("Name", VolumeNameConfig),
+				HaveField("Name", VolumeNameAuth),
+				HaveField("Name", "logs"),
+			))
+			c := podSpec.Containers[0]
+			Expect(c.Name).To(Equal(ContainerNameSVN))
+			Expect(c.Resources.Requests.Memory().String()).To(Equal("256Mi"))
+			Expect(c.Env).To(ContainElement(corev1.EnvVar{Name: "TZ", Value: "Asia/Tokyo"}))
+			Expect(c.VolumeMounts).To(ContainElements(
+				HaveField("MountPath", VolumePathRepos),
+				HaveField("MountPath", VolumePathConfig),
+				HaveField("MountPath", "/var/log/apache2"),
+			))
+		})
+
+		It("should let the pods watch their configuration if spec.configSync is API", func() {
+			controllerReconciler := &SVNServerReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+			reconcileServer := func() {
+				_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+				ExpectWithOffset(1, err).NotTo(HaveOccurred())
+			}
+			s := &svnv1alpha1.SVNServer{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, s)).To(Succeed())
+			s.Spec.ConfigSync = svnv1alpha1.ConfigSyncAPI
+			s.Spec.PodTemplate.ServiceAccountName = "svn"
+			Expect(k8sClient.Update(ctx, s)).To(Succeed())
+			reconcileServer()
+
+			role := &rbacv1.Role{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, role)).To(Succeed())
+			Expect(role.Rules).To(ConsistOf(HaveField("ResourceNames", []string{resourceName})))
+			binding := &rbacv1.RoleBinding{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, binding)).To(Succeed())
+			Expect(binding.Subjects).To(ConsistOf(HaveField("Name", "svn")))
+
+			ss := &appsv1.StatefulSet{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, ss)).To(Succeed())
+			Expect(ss.Spec.Template.Spec.Volumes).To(ContainElement(And(
+				HaveField("Name", VolumeNameConfig),
+				HaveField("EmptyDir", Not(BeNil())),
+			)))
+			Expect(ss.Spec.Template.Spec.Containers[0].Env).To(ContainElement(
+				corev1.EnvVar{Name: EnvConfigSync, Value: svnv1alpha1.ConfigSyncAPI},
+			))
+
+			By("using a dedicated ServiceAccount instead of the default one")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, s)).To(Succeed())
+			s.Spec.PodTemplate.ServiceAccountName = ""
+			Expect(k8sClient.Update(ctx, s)).To(Succeed())
+			reconcileServer()
+			sa := &corev1.ServiceAccount{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, sa)).To(Succeed())
+			Expect(metav1.IsControlledBy(sa, s)).To(BeTrue())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, binding)).To(Succeed())
+			Expect(binding.Subjects).To(ConsistOf(HaveField("Name", resourceName)))
+			Expect(k8sClient.Get(ctx, typeNamespacedName, ss)).To(Succeed())
+			Expect(ss.Spec.Template.Spec.ServiceAccountName).To(Equal(resourceName))
+
+			By("syncing the configuration through volumes again")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, s)).To(Succeed())
+			s.Spec.ConfigSync = svnv1alpha1.ConfigSyncVolume
+			Expect(k8sClient.Update(ctx, s)).To(Succeed())
+			reconcileServer()
+			Expect(errors.IsNotFound(k8sClient.Get(ctx, typeNamespacedName, &corev1.ServiceAccount{}))).To(BeTrue())
+			Expect(errors.IsNotFound(k8sClient.Get(ctx, typeNamespacedName, &rbacv1.Role{}))).To(BeTrue())
+			Expect(errors.IsNotFound(k8sClient.Get(ctx, typeNamespacedName, &rbacv1.RoleBinding{}))).To(BeTrue())
+		})
+
+		It("should tune Apache with spec.apache", func() {
+			controllerReconciler := &SVNServerReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+			s := &svnv1alpha1.SVNServer{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, s)).To(Succeed())
+			cacheSize := resource.MustParse("64Mi")
+			s.Spec.Apache = &svnv1alpha1.ApacheSpec{
+				URLPrefix:         "/svn/",
+				TimeoutSeconds:    600,
+				ListParentPath:    true,
+				InMemoryCacheSize: &cacheSize,
+			}
+			Expect(k8sClient.Update(ctx, s)).To(Succeed())
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			cm := &corev1.ConfigMap{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, cm)).To(Succeed())
+			Expect(cm.Data[ConfigMapKeyApacheConf]).To(And(
+				ContainSubstring("ServerName "+resourceName+"-client.default.svc\n"),
+				ContainSubstring("Timeout 600\n"),
+				ContainSubstring("SVNInMemoryCacheSize 65536\n"),
+				ContainSubstring("<Location /svn/>\n"),
+				ContainSubstring("SVNListParentPath On\n"),
+			))
+		})
+
+		It("should expose the repositories through an Ingress", func() {
+			controllerReconciler := &SVNServerReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+			reconcileServer := func() {
+				_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+				ExpectWithOffset(1, err).NotTo(HaveOccurred())
+			}
+
+			By("specifying spec.ingress")
+			s := &svnv1alpha1.SVNServer{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, s)).To(Succeed())
+			s.Spec.Ingress = &svnv1alpha1.IngressSpec{
+				Host: "svn.example.com",
+				TLS:  &svnv1alpha1.IngressTLS{IssuerRef: &svnv1alpha1.IssuerRef{Kind: "ClusterIssuer", Name: "letsencrypt"}},
+			}
+			Expect(k8sClient.Update(ctx, s)).To(Succeed())
+			reconcileServer()
+
+			ing := &networkingv1.Ingress{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, ing)).To(Succeed())
+			Expect(ing.Annotations).To(HaveKeyWithValue(AnnotationCertManagerClusterIssuer, "letsencrypt"))
+			Expect(ing.Spec.TLS).To(ConsistOf(networkingv1.IngressTLS{
+				Hosts:      []string{"svn.example.com"},
+				SecretName: resourceName + svnv1alpha1.TLSSecretSuffix,
+			}))
+			Expect(ing.Spec.Rules).To(HaveLen(1))
+			Expect(ing.Spec.Rules[0].HTTP.Paths[0].Path).To(Equal(svnv1alpha1.DefaultURLPrefix))
+			Expect(k8sClient.Get(ctx, typeNamespacedName, s)).To(Succeed())
+			Expect(s.Status.URL).To(Equal("https://svn.example.com/repos/"))
+
+			By("removing spec.ingress")
+			s.Spec.Ingress = nil
+			Expect(k8sClient.Update(ctx, s)).To(Succeed())
+			reconcileServer()
+			Expect(errors.IsNotFound(k8sClient.Get(ctx, typeNamespacedName, &networkingv1.Ingress{}))).To(BeTrue())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, s)).To(Succeed())
+			Expect(s.Status.URL).To(BeEmpty())
+		})
+	})
+})
+
+var _ = Describe("mountRepositorySources", func() {
+	sourced := func(name string, load *svnv1alpha1.LoadStatus) svnv1alpha1.SVNRepository {
+		return svnv1alpha1.SVNRepository{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: svnv1alpha1.SVNRepositorySpec{
+				Source: &svnv1alpha1.RepositorySource{ConfigMap: &corev1.ConfigMapKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: name},
+					Key:                  "dump",
+				}},
+			},
+			Status: svnv1alpha1.SVNRepositoryStatus{Load: load},
+		}
+	}
+	mountedConfigMaps := func(repos ...svnv1alpha1.SVNRepository) []string {
+		ss := &appsv1.StatefulSet{}
+		ss.Spec.Template.Spec.Containers = []corev1.Container{{Name: ContainerNameSVN}}
+		mountRepositorySources(&svnv1alpha1.SVNRepositoryList{Items: repos}, ss)
+		var names []string
+		for _, v := range ss.Spec.Template.Spec.Volumes {
+			names = append(names, v.ConfigMap.Name)
+		}
+		Expect(ss.Spec.Template.Spec.Containers[0].VolumeMounts).To(HaveLen(len(names)))
+		return names
+	}
+	completed := &svnv1alpha1.LoadStatus{Phase: svnv1alpha1.LoadPhaseCompleted}
+	failed := &svnv1alpha1.LoadStatus{Phase: svnv1alpha1.LoadPhaseFailed}
+
+	It("unmounts sources of completed loads", func() {
+		Expect(mountedConfigMaps(sourced("done", completed), sourced("failed", failed))).To(ConsistOf("failed"))
+	})
+
+	It("keeps sources of completed loads while others are loading", func() {
+		Expect(mountedConfigMaps(sourced("done", completed), sourced("new", nil))).To(ConsistOf("done", "new"))
+	})
+})
+
+var _ = Describe("Child conditions", func() {
+	ctx := context.Background()
+	key := types.NamespacedName{Namespace: "default", Name: "svn"}
+
+	repo := func(server string) *svnv1alpha1.SVNRepository {
+		return &svnv1alpha1.SVNRepository{
+			ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: "repo"},
+			Spec:       svnv1alpha1.SVNRepositorySpec{SVNServer: server},
+		}
+	}
+	group := func(name string, members ...string) *svnv1alpha1.SVNGroup {
+		g := &svnv1alpha1.SVNGroup{
+			ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: name},
+			Spec:       svnv1alpha1.SVNGroupSpec{SVNServer: key.Name},
+		}
+		for _, m := range members {
+			g.Spec.Groups = append(g.Spec.Groups, svnv1alpha1.GroupRef{Name: m})
+		}
+		return g
+	}
+	user := func(name string, groups ...string) *svnv1alpha1.SVNUser {
+		u := &svnv1alpha1.SVNUser{
+			ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: name},
+			Spec:       svnv1alpha1.SVNUserSpec{SVNServer: key.Name},
+		}
+		for _, g := range groups {
+			u.Spec.Groups = append(u.Spec.Groups, svnv1alpha1.GroupRef{Name: g})
+		}
+		return u
+	}
+	conditionsOf := func(r *SVNServerReconciler, obj client.Object) []metav1.Condition {
+		ExpectWithOffset(1, r.Get(ctx, client.ObjectKeyFromObject(obj), obj)).To(Succeed())
+		switch obj := obj.(type) {
+		case *svnv1alpha1.SVNRepository:
+			return obj.Status.Conditions
+		case *svnv1alpha1.SVNGroup:
+			return obj.Status.Conditions
+		case *svnv1alpha1.SVNUser:
+			return obj.Status.Conditions
+		}
+		return nil
+	}
+
+	It("marks children of SVNServers that do not exist as Degraded", func() {
+		r, _ := newFakeReconciler(repo(key.Name), group("devs"), user("noel"))
+		Expect(r.Reconcile(ctx, reconcile.Request{NamespacedName: key})).Error().NotTo(HaveOccurred())
+
+		for _, obj := range []client.Object{repo(key.Name), group("devs"), user("noel")} {
+			conds := conditionsOf(r, obj)
+			Expect(conds).To(ContainElements(
+				conditionMatcher(svnv1alpha1.ConditionTypeDegraded, metav1.ConditionTrue, svnv1alpha1.ReasonSVNServerNotFound),
+				conditionMatcher(svnv1alpha1.ConditionTypeReady, metav1.ConditionFalse, svnv1alpha1.ReasonSVNServerNotFound),
+			), "%T %s", obj, obj.GetName())
+			Expect(meta.FindStatusCondition(conds, svnv1alpha1.ConditionTypeDegraded).Message).To(Equal(`SVNServer "svn" does not exist`))
+		}
+	})
+
+	It("leaves children of other SVNServers alone", func() {
+		r, _ := newFakeReconciler(repo("other"))
+		Expect(r.Reconcile(ctx, reconcile.Request{NamespacedName: key})).Error().NotTo(HaveOccurred())
+		Expect(conditionsOf(r, repo("other"))).To(BeEmpty())
+	})
+
+	It("marks users in unknown or invalid groups as Degraded", func() {
+		server := &svnv1alpha1.SVNServer{ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name}}
+		objs := []client.Object{
+			server,
+			group("devs"),
+			group("cycle", "cycle"),
+			user("noel", "devs"),
+			user("liam", "unknown"),
+			user("paul", "cycle"),
+		}
+		r, _ := newFakeReconciler(objs...)
+		Expect(r.Reconcile(ctx, reconcile.Request{NamespacedName: key})).Error().NotTo(HaveOccurred())
+
+		degraded := func(obj client.Object, message string) {
+			conds := conditionsOf(r, obj)
+			ExpectWithOffset(1, conds).To(ContainElement(
+				conditionMatcher(svnv1alpha1.ConditionTypeDegraded, metav1.ConditionTrue, svnv1alpha1.ReasonInvalidGroup),
+			), "%T %s", obj, obj.GetName())
+			ExpectWithOffset(1, meta.FindStatusCondition(conds, svnv1alpha1.ConditionTypeDegraded).Message).To(ContainSubstring(message))
+		}
+		degraded(group("cycle"), "groups form a cycle")
+		degraded(user("liam"), `group "unknown" does not exist`)
+		degraded(user("paul"), `group "cycle" is invalid`)
+
+		// Valid ones wait for the pods to apply the configuration.
+		for _, obj := range []client.Object{group("devs"), user("noel")} {
+			Expect(conditionsOf(r, obj)).To(ContainElement(
+				conditionMatcher(svnv1alpha1.ConditionTypeProgressing, metav1.ConditionTrue, svnv1alpha1.ReasonApplyingConfig),
+			), "%T %s", obj, obj.GetName())
+		}
+	})
+
+	It("keeps users in the configuration if one of their groups is deleted", func() {
+		server := &svnv1alpha1.SVNServer{ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name}}
+		devs := group("devs")
+		devs.Spec.Permissions = []svnv1alpha1.Permission{{Repository: "repo", Path: "/", Permission: "rw"}}
+		ops := group("ops")
+		noel := user("noel", "devs", "ops")
+		noel.Spec.EncryptedPassword = "noel-hash"
+		noel.Spec.Permissions = []svnv1alpha1.Permission{{Repository: "repo", Path: "/trunk", Permission: "r"}}
+		r, _ := newFakeReconciler(server, repo(key.Name), devs, ops, noel)
+		Expect(r.Reconcile(ctx, reconcile.Request{NamespacedName: key})).Error().NotTo(HaveOccurred())
+
+		Expect(r.Delete(ctx, ops)).To(Succeed())
+		Expect(r.Reconcile(ctx, reconcile.Request{NamespacedName: key})).Error().NotTo(HaveOccurred())
+
+		cm := &corev1.ConfigMap{}
+		Expect(r.Get(ctx, key, cm)).To(Succeed())
+		Expect(cm.Data[ConfigMapKeyAuthzSVNAccessFile]).To(And(
+			ContainSubstring("devs = noel\n"),
+			ContainSubstring("@devs = rw\n"),
+			ContainSubstring("noel = r\n"),
+			Not(ContainSubstring("ops")),
+		))
+		secret := &corev1.Secret{}
+		Expect(r.Get(ctx, key, secret)).To(Succeed())
+		Expect(string(secret.Data[SecretKeyAuthUserFile])).To(ContainSubstring("noel:noel-hash\n"))
+
+		conds := conditionsOf(r, noel)
+		Expect(conds).To(ContainElement(
+			conditionMatcher(svnv1alpha1.ConditionTypeDegraded, metav1.ConditionTrue, svnv1alpha1.ReasonInvalidGroup),
+		))
+		Expect(meta.FindStatusCondition(conds, svnv1alpha1.ConditionTypeDegraded).Message).To(ContainSubstring(`group "ops" does not exist`))
+	})
+})
+
+// statusRoundTripper serves the status of server updaters regardless of the addresses of pods.
+type statusRoundTripper struct {
+	status *serverupdater.Status
+}
+
+func (t *statusRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := json.Marshal(t.status)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(bytes.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+var _ = Describe("Repository removal", func() {
+	ctx := context.Background()
+	key := types.NamespacedName{Namespace: "default", Name: "svn"}
+	repoKey := types.NamespacedName{Namespace: key.Namespace, Name: "repo"}
+
+	var r *SVNServerReconciler
+	var updater *statusRoundTripper
+	var archived []metav1.Condition
+
+	BeforeEach(func() {
+		server := &svnv1alpha1.SVNServer{ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name}}
+		repo := &svnv1alpha1.SVNRepository{
+			ObjectMeta: metav1.ObjectMeta{Namespace: repoKey.Namespace, Name: repoKey.Name},
+			Spec: svnv1alpha1.SVNRepositorySpec{
+				SVNServer:      key.Name,
+				DeletionPolicy: svnv1alpha1.DeletionPolicyArchive,
+			},
+		}
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name + "-0"},
+			Status:     corev1.PodStatus{PodIP: "10.0.0.1"},
+		}
+		r, _ = newFakeReconciler(server, repo, pod)
+		updater = &statusRoundTripper{status: &serverupdater.Status{
+			Repositories: []serverupdater.RepoStatus{{Name: repoKey.Name, Exists: true}},
+		}}
+		r.HTTPClient = &http.Client{Transport: updater}
+
+		// SVNRepositories are gone as soon as their finalizers are released, so keep their last conditions.
+		archived = nil
+		r.Client = interceptor.NewClient(r.Client.(client.WithWatch), interceptor.Funcs{
+			SubResourceUpdate: func(ctx context.Context, c client.Client, subResourceName string, obj client.Object, opts ...client.SubResourceUpdateOption) error {
+				if repo, ok := obj.(*svnv1alpha1.SVNRepository); ok {
+					archived = repo.Status.Conditions
+				}
+				return c.SubResource(subResourceName).Update(ctx, obj, opts...)
+			},
+		})
+
+		Expect(r.Reconcile(ctx, reconcile.Request{NamespacedName: key})).Error().NotTo(HaveOccurred())
+		Expect(r.Get(ctx, repoKey, repo)).To(Succeed())
+		Expect(controllerutil.ContainsFinalizer(repo, FinalizerRepositoryRemoval)).To(BeTrue())
+		Expect(r.Delete(ctx, repo)).To(Succeed())
+	})
+
+	It("holds the finalizer until the server updater removes the repository", func() {
+		res, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.RequeueAfter).To(BeNumerically(">", 0))
+		repo := &svnv1alpha1.SVNRepository{}
+		Expect(r.Get(ctx, repoKey, repo)).To(Succeed())
+		Expect(controllerutil.ContainsFinalizer(repo, FinalizerRepositoryRemoval)).To(BeTrue())
+
+		By("failing to archive the repository")
+		updater.status.Repositories[0].Error = "permission denied"
+		Expect(r.Reconcile(ctx, reconcile.Request{NamespacedName: key})).Error().NotTo(HaveOccurred())
+		Expect(r.Get(ctx, repoKey, repo)).To(Succeed())
+
+		By("archiving the repository")
+		updater.status.Repositories[0] = serverupdater.RepoStatus{Name: repoKey.Name, ArchivePath: "/archive/repo"}
+		Expect(r.Reconcile(ctx, reconcile.Request{NamespacedName: key})).Error().NotTo(HaveOccurred())
+		Expect(r.Get(ctx, repoKey, repo)).To(MatchError(ContainSubstring("not found")))
+		Expect(meta.FindStatusCondition(archived, svnv1alpha1.ConditionTypeArchived)).To(HaveValue(And(
+			HaveField("Status", metav1.ConditionTrue),
+			HaveField("Reason", svnv1alpha1.ReasonRepositoryRemoved),
+			HaveField("Message", "Repository archived to /archive/repo"),
+		)))
+	})
+
+	It("releases the finalizer if the SVNServer is gone", func() {
+		Expect(r.Delete(ctx, &svnv1alpha1.SVNServer{ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name}})).To(Succeed())
+		Expect(r.Reconcile(ctx, reconcile.Request{NamespacedName: key})).Error().NotTo(HaveOccurred())
+		Expect(r.Get(ctx, repoKey, &svnv1alpha1.SVNRepository{})).To(MatchError(ContainSubstring("not found")))
+	})
+})
+
+var _ = Describe("svnContainerFor", func() {
+	It("probes Apache outside of the location that requires authentication", func() {
+		r, _ := newFakeReconciler()
+		for _, prefix := range []string{"", "/svn/", "/scm/svn/"} {
+			s := &svnv1alpha1.SVNServer{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "svn"}}
+			s.Spec.Apache = &svnv1alpha1.ApacheSpec{URLPrefix: prefix}
+			c := r.svnContainerFor(s)
+			for _, probe := range []*corev1.Probe{c.ReadinessProbe, c.LivenessProbe} {
+				Expect(probe.HTTPGet).NotTo(BeNil())
+				Expect(probe.HTTPGet.Path).NotTo(HavePrefix(s.Spec.URLPrefix()), "URL prefix %q", prefix)
+			}
+		}
+	})
+})
+
+var _ = Describe("stampConfig", func() {
+	ctx := context.Background()
+	key := types.NamespacedName{Namespace: "default", Name: "svn"}
+
+	It("keeps the ConfigMap unless the configuration changes", func() {
+		s := &svnv1alpha1.SVNServer{ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name, Generation: 1}}
+		r, _ := newFakeReconciler(s)
+		Expect(r.Reconcile(ctx, reconcile.Request{NamespacedName: key})).Error().NotTo(HaveOccurred())
+		cm := &corev1.ConfigMap{}
+		Expect(r.Get(ctx, key, cm)).To(Succeed())
+		stamped := cm.Data
+		Expect(stamped).To(HaveKeyWithValue(ConfigMapKeyGeneration, "1"))
+
+		By("changing the SVNServer without affecting the configuration")
+		Expect(r.Get(ctx, key, s)).To(Succeed())
+		s.Generation = 2
+		s.Spec.PodTemplate = svnv1alpha1.PodTemplate{Annotations: map[string]string{"team": "scm"}}
+		Expect(r.Update(ctx, s)).To(Succeed())
+		Expect(r.Reconcile(ctx, reconcile.Request{NamespacedName: key})).Error().NotTo(HaveOccurred())
+		Expect(r.Get(ctx, key, cm)).To(Succeed())
+		Expect(cm.Data).To(Equal(stamped))
+
+		By("changing the configuration")
+		Expect(r.Create(ctx, &svnv1alpha1.SVNRepository{
+			ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: "repo"},
+			Spec:       svnv1alpha1.SVNRepositorySpec{SVNServer: key.Name},
+		})).To(Succeed())
+		Expect(r.Reconcile(ctx, reconcile.Request{NamespacedName: key})).Error().NotTo(HaveOccurred())
+		Expect(r.Get(ctx, key, cm)).To(Succeed())
+		Expect(cm.Data).To(HaveKeyWithValue(ConfigMapKeyGeneration, "2"))
+		Expect(cm.Data[ConfigMapKeyConfigHash]).NotTo(Equal(stamped[ConfigMapKeyConfigHash]))
 	})
 })
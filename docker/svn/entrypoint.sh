@@ -7,12 +7,41 @@ set -e
 
 source /etc/apache2/envvars
 
-export SERVER_NAME="test.example.com"
-
 mkdir -p /svn
 chown -R www-data:www-data /svn
 
 sudo -u www-data -g www-data mkdir -p /svn/repos
-sudo -u www-data -g www-data /work/server-updater &
+# SVN_CONFIG_SYNC=API makes the server updater write the ConfigMap and the Secret into /etc/svn-config
+# and /etc/svn-auth as soon as they change in the API, instead of kubelet syncing their volumes.
+# The server updater only validates and reloads Apache if it is served.
+updater_args=(--protocols "${SVN_PROTOCOLS:-http}")
+if [ "${SVN_CONFIG_SYNC:-}" = "API" ]; then
+  updater_args+=(--watch-api --namespace "${SVN_SERVER_NAMESPACE}" --name "${SVN_SERVER_NAME}")
+fi
+sudo -u www-data -g www-data /work/server-updater "${updater_args[@]}" &
+
+# The servers read the configuration on startup, so wait until it is written.
+until [ -e /etc/svn-config/ApacheConf ] && [ -e /etc/svn-auth/AuthUserFile ]; do
+  sleep 1
+done
+
+# SVN_PROTOCOLS is a comma-separated list of protocols to serve, which defaults to http (Apache).
+protocols=",${SVN_PROTOCOLS:-http},"
+svnserve=(sudo -u www-data -g www-data svnserve --daemon --foreground --root /svn/repos --listen-port 3690 \
+  --config-file /etc/svn-config/SvnserveConf --log-file /dev/stderr)
+if [[ "${protocols}" != *,http,* ]]; then
+  exec "${svnserve[@]}"
+fi
+if [[ "${protocols}" == *,svn,* ]]; then
+  "${svnserve[@]}" &
+fi
+
+defines=()
+if [ "${SVN_TLS:-}" = "true" ]; then
+  defines+=(-DTLS)
+  if [ "${SVN_REDIRECT_HTTP:-}" = "true" ]; then
+    defines+=(-DREDIRECT_HTTP)
+  fi
+fi
 
-exec apache2 -DFOREGROUND "$@"
+exec apache2 -DFOREGROUND "${defines[@]}" "$@"
@@ -0,0 +1,106 @@
+/*
+Copyright 2024 markzhang.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package serverupdater
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// dataDirName is a symlink to the directory that holds the current files, as in volumes of ConfigMaps.
+// See https://github.com/kubernetes/kubernetes/blob/master/pkg/volume/util/atomic_writer.go
+const dataDirName = "..data"
+
+// WriteFiles replaces all files in dir with files atomically, so that readers never see a mix of old and new files.
+// The files are written into a new hidden directory that the `..data` symlink is switched to,
+// and each file is a symlink through `..data`. It returns false without writing anything if the files are unchanged.
+func WriteFiles(dir string, files map[string][]byte, perm os.FileMode) (bool, error) {
+	if filesUnchanged(dir, files) {
+		return false, nil
+	}
+	tsDir, err := os.MkdirTemp(dir, "..")
+	if err != nil {
+		return false, err
+	}
+	if err := os.Chmod(tsDir, 0755); err != nil {
+		return false, err
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(tsDir, name), data, perm); err != nil {
+			os.RemoveAll(tsDir)
+			return false, err
+		}
+	}
+
+	oldTSDir, _ := os.Readlink(filepath.Join(dir, dataDirName))
+	tmpLink := filepath.Join(dir, dataDirName+"_tmp")
+	os.Remove(tmpLink)
+	if err := os.Symlink(filepath.Base(tsDir), tmpLink); err != nil {
+		return false, err
+	}
+	if err := os.Rename(tmpLink, filepath.Join(dir, dataDirName)); err != nil {
+		return false, err
+	}
+
+	for name := range files {
+		link := filepath.Join(dir, name)
+		if _, err := os.Lstat(link); err == nil {
+			continue
+		}
+		if err := os.Symlink(filepath.Join(dataDirName, name), link); err != nil {
+			return true, err
+		}
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return true, err
+	}
+	for _, e := range entries {
+		name := e.Name()
+		if strings.HasPrefix(name, "..") {
+			continue
+		}
+		if _, ok := files[name]; !ok && e.Type()&os.ModeSymlink != 0 {
+			if err := os.Remove(filepath.Join(dir, name)); err != nil {
+				return true, err
+			}
+		}
+	}
+	if oldTSDir != "" {
+		if err := os.RemoveAll(filepath.Join(dir, oldTSDir)); err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
+
+// filesUnchanged reports whether dir has exactly the files with the same contents.
+func filesUnchanged(dir string, files map[string][]byte) bool {
+	entries, err := os.ReadDir(filepath.Join(dir, dataDirName))
+	if err != nil || len(entries) != len(files) {
+		return false
+	}
+	for name, data := range files {
+		current, err := os.ReadFile(filepath.Join(dir, dataDirName, name))
+		if err != nil || !bytes.Equal(current, data) {
+			return false
+		}
+	}
+	return true
+}
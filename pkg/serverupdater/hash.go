@@ -0,0 +1,116 @@
+/*
+Copyright 2024 markzhang.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package serverupdater
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Here is a list of files in the config directory that identify the configuration.
+const (
+	// ConfigHashFile is the hash of the configuration computed by HashConfig, which is excluded from the hash.
+	ConfigHashFile = "ConfigHash"
+	// GenerationFile is the generation of the SVNServer that the configuration has last changed at,
+	// which is excluded from the hash too.
+	GenerationFile = "Generation"
+)
+
+// HashConfig returns a hash of the files in the config directory and the auth directory.
+// The controller hashes the ConfigMap and the Secret it generates, and the server updater hashes the files
+// it has applied, so that the controller can tell whether the server runs with the latest configuration.
+// Only the rendered files are hashed, so that changes to the SVNServer which do not affect them
+// do not make the pods apply the configuration again.
+func HashConfig(config map[string]string, auth map[string][]byte) string {
+	h := sha256.New()
+	write := func(dir, name string, data []byte) {
+		fmt.Fprintf(h, "%s/%s\x00%d\x00", dir, name, len(data))
+		h.Write(data)
+	}
+	for _, name := range sortedKeys(config) {
+		if name != ConfigHashFile && name != GenerationFile {
+			write("config", name, []byte(config[name]))
+		}
+	}
+	for _, name := range sortedKeys(auth) {
+		write("auth", name, auth[name])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// currentConfig hashes the files in ConfigDir and AuthDir, and reads the generation that they are generated from.
+func (u *Updater) currentConfig() (hash string, generation int64, err error) {
+	config, err := readFiles(u.ConfigDir)
+	if err != nil {
+		return "", 0, err
+	}
+	auth, err := readFiles(u.AuthDir)
+	if err != nil {
+		return "", 0, err
+	}
+	strConfig := make(map[string]string, len(config))
+	for k, v := range config {
+		strConfig[k] = string(v)
+	}
+	hash = HashConfig(strConfig, auth)
+	if expected := strings.TrimSpace(strConfig[ConfigHashFile]); expected != "" && expected != hash {
+		// Volumes of the ConfigMap and the Secret are synced separately.
+		u.Log.Info("config files are partially synced", "expected", expected, "actual", hash)
+	}
+	if g, ok := strConfig[GenerationFile]; ok {
+		generation, err = strconv.ParseInt(strings.TrimSpace(g), 10, 64)
+		if err != nil {
+			return "", 0, fmt.Errorf("invalid generation %q: %w", g, err)
+		}
+	}
+	return hash, generation, nil
+}
+
+// readFiles reads the files in dir, except hidden ones such as `..data` of volumes of ConfigMaps.
+func readFiles(dir string) (map[string][]byte, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string][]byte, len(entries))
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		files[e.Name()] = data
+	}
+	return files, nil
+}
@@ -0,0 +1,38 @@
+package serverupdater_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/markzhang0928/svn-operator/pkg/serverupdater"
+)
+
+var _ = Describe("HashConfig", func() {
+	config := map[string]string{"Repos": "repositories: []\n", serverupdater.GenerationFile: "1"}
+	auth := map[string][]byte{"AuthUserFile": []byte("noel:hash\n")}
+
+	It("ignores the hash itself", func() {
+		hash := serverupdater.HashConfig(config, auth)
+		stamped := map[string]string{serverupdater.ConfigHashFile: hash}
+		for k, v := range config {
+			stamped[k] = v
+		}
+		Expect(serverupdater.HashConfig(stamped, auth)).To(Equal(hash))
+	})
+
+	It("distinguishes the config directory from the auth directory", func() {
+		moved := map[string][]byte{"Repos": []byte(config["Repos"]), "AuthUserFile": auth["AuthUserFile"]}
+		Expect(serverupdater.HashConfig(map[string]string{serverupdater.GenerationFile: "1"}, moved)).
+			NotTo(Equal(serverupdater.HashConfig(config, auth)))
+	})
+
+	It("ignores the generation", func() {
+		bumped := map[string]string{"Repos": config["Repos"], serverupdater.GenerationFile: "2"}
+		Expect(serverupdater.HashConfig(bumped, auth)).To(Equal(serverupdater.HashConfig(config, auth)))
+	})
+
+	It("changes with the rendered files", func() {
+		changed := map[string]string{"Repos": "repositories: [{name: hoge}]\n", serverupdater.GenerationFile: "1"}
+		Expect(serverupdater.HashConfig(changed, auth)).NotTo(Equal(serverupdater.HashConfig(config, auth)))
+	})
+})
@@ -0,0 +1,233 @@
+/*
+Copyright 2024 markzhang.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package serverupdater
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/markzhang0928/svn-operator/pkg/svnconfig"
+)
+
+// Phases of loading dump files.
+const (
+	LoadPhaseLoading   = "Loading"
+	LoadPhaseCompleted = "Completed"
+	LoadPhaseFailed    = "Failed"
+)
+
+// LoadStatus is a progress of loading a dump file into a repository.
+type LoadStatus struct {
+	Phase    string `json:"phase"`
+	Revision int64  `json:"revision"`
+	Error    string `json:"error,omitempty"`
+}
+
+// committedRevision matches lines that `svnadmin load` prints after each revision is committed.
+var committedRevision = regexp.MustCompile(`^-+ Committed (?:revision|new rev) (\d+)`)
+
+// errLoadCancelled is an error of loads that are cancelled because their repositories have been removed.
+var errLoadCancelled = errors.New("cancelled because the repository has been removed")
+
+// loader loads a dump file into a repository in background.
+type loader struct {
+	source svnconfig.Source
+	status LoadStatus
+
+	// ctx is cancelled by cancel when the repository is removed during the load.
+	// cancelled is set under Updater.mu, so that the repository is never moved into ReposDir after that.
+	ctx       context.Context
+	cancel    context.CancelFunc
+	cancelled bool
+}
+
+// startLoading starts loading the dump file into a new repository unless it has already been started.
+// A load that failed is not retried until the source is changed, while a completed load is started
+// again since the repository has been removed after that.
+func (u *Updater) startLoading(entry *svnconfig.RepoEntry) RepoStatus {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.loads == nil {
+		u.loads = map[string]*loader{}
+	}
+	l, ok := u.loads[entry.Name]
+	if !ok || l.source != *entry.Source || l.status.Phase == LoadPhaseCompleted {
+		l = &loader{source: *entry.Source, status: LoadStatus{Phase: LoadPhaseLoading}}
+		l.ctx, l.cancel = context.WithCancel(context.Background())
+		u.loads[entry.Name] = l
+		go u.load(entry.Name, l)
+	}
+	status := l.status
+	return RepoStatus{Name: entry.Name, Exists: status.Phase == LoadPhaseCompleted, Load: &status}
+}
+
+// load creates a repository in WorkDir, loads the dump file into it and then moves it into ReposDir
+// so that incomplete repositories are never served.
+func (u *Updater) load(name string, l *loader) {
+	log := u.Log.WithValues("repository", name)
+	log.Info("loading dump file", "file", l.source.File, "url", l.source.URL)
+	err := u.loadInto(name, l)
+	l.cancel()
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if l.cancelled {
+		log.Info("cancelled loading dump file since the repository has been removed")
+		if err := os.RemoveAll(filepath.Join(u.WorkDir, name)); err != nil {
+			log.Error(err, "failed to clean up repository")
+		}
+		l.status.Phase = LoadPhaseFailed
+		l.status.Error = errLoadCancelled.Error()
+		return
+	}
+	if err != nil {
+		log.Error(err, "failed to load dump file")
+		l.status.Phase = LoadPhaseFailed
+		l.status.Error = err.Error()
+		return
+	}
+	log.Info("loaded dump file", "revision", l.status.Revision)
+	l.status.Phase = LoadPhaseCompleted
+}
+
+func (u *Updater) loadInto(name string, l *loader) error {
+	work := filepath.Join(u.WorkDir, name)
+	if err := os.RemoveAll(work); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(u.WorkDir, 0755); err != nil {
+		return err
+	}
+	if err := u.runCommand(u.SvnAdmin, "create", work); err != nil {
+		return err
+	}
+
+	dump, err := openSource(l.ctx, &l.source)
+	if err != nil {
+		return err
+	}
+	defer dump.Close()
+
+	stderr := bytes.NewBuffer(nil)
+	pr, pw := io.Pipe()
+	cmd := exec.CommandContext(l.ctx, u.SvnAdmin, "load", work)
+	cmd.Stdin = dump
+	cmd.Stdout = pw
+	cmd.Stderr = stderr
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		scanner := bufio.NewScanner(pr)
+		for scanner.Scan() {
+			m := committedRevision.FindStringSubmatch(scanner.Text())
+			if m == nil {
+				continue
+			}
+			rev, err := strconv.ParseInt(m[1], 10, 64)
+			if err != nil {
+				continue
+			}
+			u.mu.Lock()
+			l.status.Revision = rev
+			u.mu.Unlock()
+		}
+		// Drain the rest so that svnadmin never blocks on writing.
+		_, _ = io.Copy(io.Discard, pr)
+	}()
+	err = cmd.Wait()
+	pw.Close()
+	<-done
+	if err != nil {
+		return fmt.Errorf("svnadmin load failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	if err := os.MkdirAll(u.ReposDir, 0755); err != nil {
+		return err
+	}
+	// The repository is moved under the lock, so that it is either removed afterwards or never moved.
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if l.cancelled {
+		return errLoadCancelled
+	}
+	return os.Rename(work, filepath.Join(u.ReposDir, name))
+}
+
+// cancelLoad cancels loading the dump file into the repository, which is being removed.
+func (u *Updater) cancelLoad(name string) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	l, ok := u.loads[name]
+	if !ok {
+		return
+	}
+	if l.status.Phase == LoadPhaseLoading {
+		u.Log.Info("cancelling load of dump file", "repository", name)
+		l.cancelled = true
+		l.cancel()
+	}
+	delete(u.loads, name)
+}
+
+func openSource(ctx context.Context, src *svnconfig.Source) (io.ReadCloser, error) {
+	if src.File != "" {
+		return os.Open(src.File)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, src.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected status code from %s: %d", src.URL, resp.StatusCode)
+	}
+	return resp.Body, nil
+}
+
+// youngestRevision returns the youngest revision of the FSFS repository, or 0 if it cannot be determined.
+func youngestRevision(repo string) int64 {
+	current, err := os.ReadFile(filepath.Join(repo, "db", "current"))
+	if err != nil {
+		return 0
+	}
+	fields := strings.Fields(string(current))
+	if len(fields) == 0 {
+		return 0
+	}
+	rev, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return rev
+}
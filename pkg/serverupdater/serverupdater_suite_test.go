@@ -0,0 +1,13 @@
+package serverupdater_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestServerupdater(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Serverupdater Suite")
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2024 markzhang.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package serverupdater
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+)
+
+// StatusPath is a path of the endpoint that serves Status.
+const StatusPath = "/status"
+
+// Status is a state of SVN repositories that the server updater has applied.
+type Status struct {
+	// Config is the configuration that has been applied most recently.
+	// It is nil until the configuration is applied for the first time.
+	Config *ConfigStatus `json:"config,omitempty"`
+
+	Repositories []RepoStatus `json:"repositories"`
+}
+
+// ConfigStatus identifies a configuration that the server updater has applied.
+type ConfigStatus struct {
+	// Hash is a hash of the configuration computed by HashConfig.
+	Hash string `json:"hash"`
+
+	// Generation is the generation of the SVNServer that the configuration has last changed at.
+	Generation int64 `json:"generation,omitempty"`
+
+	// Error is an error occurred while applying the configuration.
+	Error string `json:"error,omitempty"`
+}
+
+// RepoStatus is a state of a single SVN repository.
+type RepoStatus struct {
+	Name string `json:"name"`
+
+	// Exists is true if the repository exists in ReposDir.
+	Exists bool `json:"exists"`
+
+	// ArchivePath is a path that the repository has been archived to.
+	ArchivePath string `json:"archivePath,omitempty"`
+
+	// Load is a progress of loading the dump file that the repository is created from.
+	Load *LoadStatus `json:"load,omitempty"`
+
+	// Error is an error occurred while applying the latest configuration to the repository.
+	Error string `json:"error,omitempty"`
+}
+
+// Find returns the status of the given repository, or nil if the repository is not found.
+func (s *Status) Find(name string) *RepoStatus {
+	for i := range s.Repositories {
+		if s.Repositories[i].Name == name {
+			return &s.Repositories[i]
+		}
+	}
+	return nil
+}
+
+// Status returns the state of the repositories that are applied most recently.
+func (u *Updater) Status() *Status {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	repos := make([]RepoStatus, 0, len(u.status))
+	for _, st := range u.status {
+		// Dump files are loaded in background, so report the latest progress.
+		if l, ok := u.loads[st.Name]; ok && st.Load != nil && st.Load.Phase == LoadPhaseLoading {
+			load := l.status
+			st.Load = &load
+			st.Exists = load.Phase == LoadPhaseCompleted
+			if load.Phase == LoadPhaseFailed {
+				st.Error = load.Error
+			}
+		}
+		repos = append(repos, st)
+	}
+	sort.Slice(repos, func(i, j int) bool {
+		return repos[i].Name < repos[j].Name
+	})
+	var config *ConfigStatus
+	if u.config != nil {
+		c := *u.config
+		config = &c
+	}
+	return &Status{Config: config, Repositories: repos}
+}
+
+// ServeHTTP serves Status in JSON.
+func (u *Updater) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(u.Status()); err != nil {
+		u.Log.Error(err, "failed to write status")
+	}
+}
+
+// FetchStatus fetches Status from the server updater that listens on the given address.
+func FetchStatus(ctx context.Context, c *http.Client, addr string) (*Status, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+addr+StatusPath, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code from %s: %d", addr, resp.StatusCode)
+	}
+	var status Status
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
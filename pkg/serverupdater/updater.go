@@ -22,11 +22,14 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-logr/logr"
@@ -35,42 +38,110 @@ import (
 	"github.com/markzhang0928/svn-operator/pkg/svnconfig"
 )
 
+const (
+	// archiveSeparator separates names of archived repositories from timestamps.
+	// This must be a character that is not allowed in names of SVNRepositories.
+	archiveSeparator  = "_"
+	archiveTimeFormat = "20060102T150405Z"
+)
+
 // Updater updates SVN repositories and Apache Servers.
 type Updater struct {
 	// InitdScript is a path to apache init script (e.g. /etc/init.d/httpd)
+	// Apache is neither validated nor reloaded if it is empty, e.g. if the server only serves svn://.
 	InitdScript string
 
+	// Apachectl is a path to the `apachectl` command, which validates the configuration before Apache is reloaded.
+	// The configuration is not validated if it is empty.
+	Apachectl string
+
+	// PIDFile is a path to the PID file of Apache. Apache is not reloaded until the file exists,
+	// since it reads the latest configuration when it starts. Apache is always reloaded if it is empty.
+	PIDFile string
+
 	// SvnAdmin is a path to the `svnadmin` command.
 	SvnAdmin string
 
+	// Svn is a path to the `svn` command.
+	Svn string
+
 	// ReposConfig is a path to a set of definitions of repositories that the server has.
 	ReposConfig string
 
+	// ConfigDir and AuthDir are directories that the ConfigMap and the Secret of the server are in.
+	// The files in them are hashed to report which configuration has been applied.
+	// The configuration is not reported if ConfigDir is empty.
+	ConfigDir string
+	AuthDir   string
+
 	// ReposDir is a path to a directory that SVN repositories resides in.
 	ReposDir string
 
+	// ArchiveDir is a path to a directory that archived SVN repositories are moved into.
+	ArchiveDir string
+
+	// WorkDir is a path to a directory that dump files are loaded in before the repositories are moved into ReposDir.
+	// It should be on the same filesystem as ReposDir.
+	WorkDir string
+
 	// Log is a logger.
 	Log logr.Logger
 
 	// TimeoutMs is a timeout in milliseconds to run command.
 	TimeoutMs int
+
+	mu     sync.Mutex
+	status map[string]RepoStatus
+	loads  map[string]*loader
+	config *ConfigStatus
 }
 
+// OnConfigChanged applies the configuration files, and records the result in Status.
+// It should be called again with the same files after it fails, so that errors that go away by themselves,
+// such as Apache not being ready to reload, are cleared from Status.
 func (u *Updater) OnConfigChanged() error {
-	if err := u.reloadApache(); err != nil {
-		return err
+	// The files are hashed before they are applied, so that the hash never claims a newer configuration
+	// than the one that has been applied even if the files change in the meantime.
+	var config ConfigStatus
+	var hashErr error
+	if u.ConfigDir != "" {
+		config.Hash, config.Generation, hashErr = u.currentConfig()
 	}
-	if err := u.createRepositories(); err != nil {
-		return err
+	// Repositories are applied even if Apache cannot be reloaded, since the running Apache keeps serving
+	// with the previous configuration until it restarts.
+	reloadErr := u.reloadApache()
+	err := errors.Join(hashErr, reloadErr, u.applyRepositories())
+	if u.ConfigDir != "" {
+		if err != nil {
+			config.Error = err.Error()
+		}
+		u.mu.Lock()
+		u.config = &config
+		u.mu.Unlock()
 	}
-	return nil
+	return err
 }
 
+// reloadApache gracefully reloads Apache if the new configuration is valid,
+// so that in-flight requests are not interrupted by a broken configuration.
 func (u *Updater) reloadApache() error {
+	if u.InitdScript == "" {
+		return nil
+	}
+	if u.Apachectl != "" {
+		if err := u.runCommand(u.Apachectl, "configtest"); err != nil {
+			// The configuration is already on disk, so only the running Apache keeps the previous one.
+			return fmt.Errorf("invalid Apache configuration; Apache was not reloaded and will fail to start if the pod restarts: %w", err)
+		}
+	}
+	if u.PIDFile != "" && !fileExists(u.PIDFile) {
+		u.Log.Info("Apache is not running yet; it will read the configuration when it starts")
+		return nil
+	}
 	return u.runCommand(u.InitdScript, "reload")
 }
 
-func (u *Updater) createRepositories() error {
+func (u *Updater) applyRepositories() error {
 	reposConfigFile, err := os.Open(u.ReposConfig)
 	if err != nil {
 		return err
@@ -85,21 +156,120 @@ func (u *Updater) createRepositories() error {
 	if err != nil {
 		return err
 	}
+	status := make(map[string]RepoStatus, len(reposConfig.Repositories))
+	var errs []error
 	for i := range reposConfig.Repositories {
-		err = u.createRepository(reposConfig.Repositories[i].Name)
+		entry := &reposConfig.Repositories[i]
+		var st RepoStatus
+		switch entry.Removal {
+		case svnconfig.RemovalArchive:
+			st, err = u.archiveRepository(entry.Name)
+		case svnconfig.RemovalDelete:
+			st, err = u.deleteRepository(entry.Name)
+		default:
+			st, err = u.createRepository(entry)
+		}
 		if err != nil {
-			return err
+			st.Error = err.Error()
+			errs = append(errs, err)
 		}
+		status[entry.Name] = st
 	}
-	return nil
+	u.mu.Lock()
+	u.status = status
+	u.mu.Unlock()
+	return errors.Join(errs...)
 }
 
-func (u *Updater) createRepository(name string) error {
+func (u *Updater) createRepository(entry *svnconfig.RepoEntry) (RepoStatus, error) {
+	name := entry.Name
 	dest := filepath.Join(u.ReposDir, name)
 	if fileExists(dest) {
+		st := RepoStatus{Name: name, Exists: true}
+		if entry.Source != nil {
+			st.Load = &LoadStatus{Phase: LoadPhaseCompleted, Revision: youngestRevision(dest)}
+		}
+		return st, nil
+	}
+	if entry.Source != nil {
+		// The initial layout is not committed since the dump file has its own layout.
+		st := u.startLoading(entry)
+		if st.Load.Phase == LoadPhaseFailed {
+			return st, errors.New(st.Load.Error)
+		}
+		return st, nil
+	}
+	if err := u.runCommand(u.SvnAdmin, "create", dest); err != nil {
+		return RepoStatus{Name: name}, err
+	}
+	if err := u.commitInitialLayout(dest, entry.InitialLayout); err != nil {
+		// Remove the incomplete repository so that it is created again next time.
+		if rmErr := os.RemoveAll(dest); rmErr != nil {
+			u.Log.Error(rmErr, "failed to clean up repository", "repository", name)
+		}
+		return RepoStatus{Name: name}, err
+	}
+	return RepoStatus{Name: name, Exists: true}, nil
+}
+
+// commitInitialLayout commits all directories in the layout as a single revision.
+func (u *Updater) commitInitialLayout(repo string, layout []string) error {
+	if len(layout) == 0 {
 		return nil
 	}
-	return u.runCommand(u.SvnAdmin, "create", dest)
+	cmd := []string{u.Svn, "mkdir", "--parents", "--non-interactive", "-m", "Create initial layout"}
+	for _, dir := range layout {
+		cmd = append(cmd, "file://"+filepath.ToSlash(filepath.Join(repo, dir)))
+	}
+	return u.runCommand(cmd...)
+}
+
+// archiveRepository moves the repository into ArchiveDir.
+// The name of the archived repository is suffixed with the time when it is archived.
+func (u *Updater) archiveRepository(name string) (RepoStatus, error) {
+	u.cancelLoad(name)
+	src := filepath.Join(u.ReposDir, name)
+	if !fileExists(src) {
+		archived, err := u.latestArchiveOf(name)
+		return RepoStatus{Name: name, ArchivePath: archived}, err
+	}
+	if err := os.MkdirAll(u.ArchiveDir, 0755); err != nil {
+		return RepoStatus{Name: name, Exists: true}, err
+	}
+	dest := filepath.Join(u.ArchiveDir, name+archiveSeparator+time.Now().UTC().Format(archiveTimeFormat))
+	u.Log.Info("archiving repository", "repository", name, "destination", dest)
+	if err := os.Rename(src, dest); err != nil {
+		return RepoStatus{Name: name, Exists: true}, err
+	}
+	return RepoStatus{Name: name, ArchivePath: dest}, nil
+}
+
+func (u *Updater) deleteRepository(name string) (RepoStatus, error) {
+	u.cancelLoad(name)
+	dest := filepath.Join(u.ReposDir, name)
+	if !fileExists(dest) {
+		return RepoStatus{Name: name}, nil
+	}
+	u.Log.Info("deleting repository", "repository", name)
+	if err := os.RemoveAll(dest); err != nil {
+		return RepoStatus{Name: name, Exists: fileExists(dest)}, err
+	}
+	return RepoStatus{Name: name}, nil
+}
+
+// latestArchiveOf returns the path of the latest archive of the repository, or an empty string
+// if the repository has never been archived.
+func (u *Updater) latestArchiveOf(name string) (string, error) {
+	matches, err := filepath.Glob(filepath.Join(u.ArchiveDir, name+archiveSeparator+"*"))
+	if err != nil {
+		return "", fmt.Errorf("failed to find archives of %s: %w", name, err)
+	}
+	if len(matches) == 0 {
+		return "", nil
+	}
+	// The timestamps are formatted so that they can be sorted lexicographically.
+	sort.Strings(matches)
+	return matches[len(matches)-1], nil
 }
 
 func (u *Updater) runCommand(cmd ...string) error {
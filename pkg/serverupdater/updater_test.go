@@ -0,0 +1,279 @@
+package serverupdater_test
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"sigs.k8s.io/yaml"
+
+	"github.com/markzhang0928/svn-operator/pkg/serverupdater"
+	"github.com/markzhang0928/svn-operator/pkg/svnconfig"
+)
+
+var _ = Describe("Updater", func() {
+	var dir string
+	var u *serverupdater.Updater
+
+	writeReposConfig := func(entries ...svnconfig.RepoEntry) {
+		data, err := yaml.Marshal(&svnconfig.ReposConfig{Repositories: entries})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.WriteFile(u.ReposConfig, data, 0644)).To(Succeed())
+	}
+	createRepo := func(name string) {
+		Expect(os.MkdirAll(filepath.Join(u.ReposDir, name, "db"), 0755)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = os.MkdirTemp("", "serverupdater")
+		Expect(err).NotTo(HaveOccurred())
+		u = &serverupdater.Updater{
+			InitdScript: "true",
+			SvnAdmin:    "false",
+			ReposConfig: filepath.Join(dir, "Repos"),
+			ReposDir:    filepath.Join(dir, "repos"),
+			ArchiveDir:  filepath.Join(dir, "archive"),
+			Log:         logr.Discard(),
+		}
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	Context("when an existing repository is listed", func() {
+		It("reports that the repository exists", func() {
+			createRepo("hoge")
+			writeReposConfig(svnconfig.RepoEntry{Name: "hoge"})
+			Expect(u.OnConfigChanged()).To(Succeed())
+			Expect(u.Status().Repositories).To(Equal([]serverupdater.RepoStatus{
+				{Name: "hoge", Exists: true},
+			}))
+		})
+	})
+
+	Context("when the configuration of Apache is invalid", func() {
+		It("does not reload Apache but still applies the repositories", func() {
+			u.Apachectl = "false"
+			createRepo("hoge")
+			writeReposConfig(svnconfig.RepoEntry{Name: "hoge", Removal: svnconfig.RemovalDelete})
+			Expect(u.OnConfigChanged()).To(MatchError(ContainSubstring("invalid Apache configuration")))
+			Expect(filepath.Join(u.ReposDir, "hoge")).NotTo(BeADirectory())
+		})
+	})
+
+	Context("when the config directories are set", func() {
+		BeforeEach(func() {
+			u.ConfigDir = filepath.Join(dir, "config")
+			u.AuthDir = filepath.Join(dir, "auth")
+			u.ReposConfig = filepath.Join(u.ConfigDir, "Repos")
+			Expect(os.Mkdir(u.ConfigDir, 0755)).To(Succeed())
+			Expect(os.Mkdir(u.AuthDir, 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(u.ConfigDir, serverupdater.GenerationFile), []byte("3"), 0644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(u.AuthDir, "AuthUserFile"), []byte("noel:hash\n"), 0644)).To(Succeed())
+		})
+
+		It("reports the hash and the generation of the applied configuration", func() {
+			Expect(u.Status().Config).To(BeNil())
+			createRepo("hoge")
+			writeReposConfig(svnconfig.RepoEntry{Name: "hoge"})
+			Expect(u.OnConfigChanged()).To(Succeed())
+
+			repos, err := os.ReadFile(u.ReposConfig)
+			Expect(err).NotTo(HaveOccurred())
+			hash := serverupdater.HashConfig(
+				map[string]string{"Repos": string(repos), serverupdater.GenerationFile: "3"},
+				map[string][]byte{"AuthUserFile": []byte("noel:hash\n")},
+			)
+			Expect(u.Status().Config).To(Equal(&serverupdater.ConfigStatus{Hash: hash, Generation: 3}))
+		})
+
+		It("reports errors with the hash", func() {
+			u.Apachectl = "false"
+			writeReposConfig()
+			Expect(u.OnConfigChanged()).NotTo(Succeed())
+			Expect(u.Status().Config.Hash).NotTo(BeEmpty())
+			Expect(u.Status().Config.Error).To(ContainSubstring("will fail to start if the pod restarts"))
+		})
+
+		It("does not touch Apache if it is not served", func() {
+			u.InitdScript = ""
+			u.Apachectl = "false"
+			writeReposConfig()
+			Expect(u.OnConfigChanged()).To(Succeed())
+			Expect(u.Status().Config.Error).To(BeEmpty())
+		})
+
+		It("does not reload Apache until it starts", func() {
+			u.InitdScript = "false"
+			u.PIDFile = filepath.Join(dir, "apache2.pid")
+			writeReposConfig()
+			Expect(u.OnConfigChanged()).To(Succeed())
+			Expect(u.Status().Config.Error).To(BeEmpty())
+
+			By("failing to reload Apache after it starts")
+			Expect(os.WriteFile(u.PIDFile, []byte("1\n"), 0644)).To(Succeed())
+			Expect(u.OnConfigChanged()).NotTo(Succeed())
+			hash := u.Status().Config.Hash
+			Expect(u.Status().Config.Error).NotTo(BeEmpty())
+
+			By("retrying with the same configuration")
+			u.InitdScript = "true"
+			Expect(u.OnConfigChanged()).To(Succeed())
+			Expect(u.Status().Config).To(Equal(&serverupdater.ConfigStatus{Hash: hash, Generation: 3}))
+		})
+	})
+
+	Context("when a repository is deleted", func() {
+		It("removes the repository", func() {
+			createRepo("hoge")
+			writeReposConfig(svnconfig.RepoEntry{Name: "hoge", Removal: svnconfig.RemovalDelete})
+			Expect(u.OnConfigChanged()).To(Succeed())
+			Expect(filepath.Join(u.ReposDir, "hoge")).NotTo(BeADirectory())
+			Expect(u.Status().Repositories).To(Equal([]serverupdater.RepoStatus{
+				{Name: "hoge", Exists: false},
+			}))
+		})
+	})
+
+	Context("when a repository is archived", func() {
+		It("moves the repository into the archive directory", func() {
+			createRepo("hoge")
+			writeReposConfig(svnconfig.RepoEntry{Name: "hoge", Removal: svnconfig.RemovalArchive})
+			Expect(u.OnConfigChanged()).To(Succeed())
+			Expect(filepath.Join(u.ReposDir, "hoge")).NotTo(BeADirectory())
+
+			st := u.Status().Find("hoge")
+			Expect(st).NotTo(BeNil())
+			Expect(st.Exists).To(BeFalse())
+			Expect(st.ArchivePath).To(HavePrefix(filepath.Join(u.ArchiveDir, "hoge_")))
+			Expect(filepath.Join(st.ArchivePath, "db")).To(BeADirectory())
+
+			By("applying the same configuration again")
+			Expect(u.OnConfigChanged()).To(Succeed())
+			Expect(u.Status().Find("hoge").ArchivePath).To(Equal(st.ArchivePath))
+		})
+	})
+
+	Context("when a repository with an initial layout is created", func() {
+		var svnLog string
+
+		BeforeEach(func() {
+			svnLog = filepath.Join(dir, "svn.log")
+			u.SvnAdmin = writeScript(dir, "svnadmin", `mkdir -p "$2"`)
+			u.Svn = writeScript(dir, "svn", `echo "$@" >> `+svnLog)
+		})
+
+		It("commits the layout", func() {
+			writeReposConfig(svnconfig.RepoEntry{Name: "hoge", InitialLayout: []string{"trunk", "branches/qa"}})
+			Expect(u.OnConfigChanged()).To(Succeed())
+			repo := filepath.Join(u.ReposDir, "hoge")
+			expected := "mkdir --parents --non-interactive -m Create initial layout " +
+				"file://" + repo + "/trunk file://" + repo + "/branches/qa\n"
+			Expect(os.ReadFile(svnLog)).To(BeEquivalentTo(expected))
+
+			By("applying the same configuration again")
+			Expect(u.OnConfigChanged()).To(Succeed())
+			Expect(os.ReadFile(svnLog)).To(BeEquivalentTo(expected))
+		})
+
+		It("removes the repository if the layout cannot be committed", func() {
+			u.Svn = writeScript(dir, "svn", "exit 1")
+			writeReposConfig(svnconfig.RepoEntry{Name: "hoge", InitialLayout: []string{"trunk"}})
+			Expect(u.OnConfigChanged()).NotTo(Succeed())
+			Expect(filepath.Join(u.ReposDir, "hoge")).NotTo(BeADirectory())
+			Expect(u.Status().Find("hoge").Exists).To(BeFalse())
+		})
+	})
+
+	Context("when a repository is created from a dump file", func() {
+		var dump string
+
+		BeforeEach(func() {
+			dump = filepath.Join(dir, "dump")
+			u.WorkDir = filepath.Join(dir, "work")
+			u.SvnAdmin = writeScript(dir, "svnadmin", `
+case "$1" in
+create) mkdir -p "$2/db" ;;
+load)
+	cat > "$2/db/loaded"
+	echo "------- Committed revision 1 >>>"
+	echo "------- Committed revision 2 >>>"
+	;;
+esac`)
+		})
+
+		It("loads the dump file and reports the revision", func() {
+			Expect(os.WriteFile(dump, []byte("SVN-fs-dump-format-version: 2\n"), 0644)).To(Succeed())
+			writeReposConfig(svnconfig.RepoEntry{Name: "hoge", Source: &svnconfig.Source{File: dump}})
+			Expect(u.OnConfigChanged()).To(Succeed())
+			Eventually(func() *serverupdater.LoadStatus {
+				return u.Status().Find("hoge").Load
+			}).Should(Equal(&serverupdater.LoadStatus{Phase: serverupdater.LoadPhaseCompleted, Revision: 2}))
+			Expect(u.Status().Find("hoge").Exists).To(BeTrue())
+			Expect(os.ReadFile(filepath.Join(u.ReposDir, "hoge", "db", "loaded"))).
+				To(BeEquivalentTo("SVN-fs-dump-format-version: 2\n"))
+		})
+
+		It("reports the error if the dump file cannot be read", func() {
+			writeReposConfig(svnconfig.RepoEntry{Name: "hoge", Source: &svnconfig.Source{File: dump}})
+			Expect(u.OnConfigChanged()).To(Succeed())
+			Eventually(func() string {
+				return u.Status().Find("hoge").Load.Phase
+			}).Should(Equal(serverupdater.LoadPhaseFailed))
+			st := u.Status().Find("hoge")
+			Expect(st.Exists).To(BeFalse())
+			Expect(st.Error).NotTo(BeEmpty())
+			Expect(filepath.Join(u.ReposDir, "hoge")).NotTo(BeADirectory())
+
+			By("applying the same configuration again")
+			Expect(u.OnConfigChanged()).NotTo(Succeed())
+			Expect(u.Status().Find("hoge").Load.Phase).To(Equal(serverupdater.LoadPhaseFailed))
+		})
+
+		It("cancels the load if the repository is removed meanwhile", func() {
+			u.SvnAdmin = writeScript(dir, "svnadmin", `
+case "$1" in
+create) mkdir -p "$2/db" ;;
+load)
+	echo "------- Committed revision 1 >>>"
+	exec sleep 10
+	;;
+esac`)
+			Expect(os.WriteFile(dump, []byte("SVN-fs-dump-format-version: 2\n"), 0644)).To(Succeed())
+			writeReposConfig(svnconfig.RepoEntry{Name: "hoge", Source: &svnconfig.Source{File: dump}})
+			Expect(u.OnConfigChanged()).To(Succeed())
+			Eventually(func() int64 {
+				return u.Status().Find("hoge").Load.Revision
+			}).Should(BeEquivalentTo(1))
+
+			By("deleting the repository")
+			writeReposConfig(svnconfig.RepoEntry{Name: "hoge", Removal: svnconfig.RemovalDelete})
+			Expect(u.OnConfigChanged()).To(Succeed())
+			Expect(u.Status().Find("hoge")).To(Equal(&serverupdater.RepoStatus{Name: "hoge"}))
+			Eventually(filepath.Join(u.WorkDir, "hoge")).ShouldNot(BeADirectory())
+			Expect(filepath.Join(u.ReposDir, "hoge")).NotTo(BeADirectory())
+		})
+	})
+
+	Context("when a repository cannot be created", func() {
+		It("reports the error", func() {
+			writeReposConfig(svnconfig.RepoEntry{Name: "hoge"})
+			Expect(u.OnConfigChanged()).NotTo(Succeed())
+			st := u.Status().Find("hoge")
+			Expect(st).NotTo(BeNil())
+			Expect(st.Exists).To(BeFalse())
+			Expect(st.Error).NotTo(BeEmpty())
+		})
+	})
+})
+
+// writeScript writes a shell script that can be used instead of commands.
+func writeScript(dir, name, body string) string {
+	path := filepath.Join(dir, name)
+	Expect(os.WriteFile(path, []byte("#!/bin/sh\n"+body+"\n"), 0755)).To(Succeed())
+	return path
+}
@@ -0,0 +1,156 @@
+/*
+Copyright 2024 markzhang.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package serverupdater
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// ConfigWatcher watches the ConfigMap and the Secret of an SVN server through the Kubernetes API,
+// and writes them into directories as soon as they change, instead of waiting for kubelet to sync their volumes.
+type ConfigWatcher struct {
+	// Client is a client of the Kubernetes API.
+	Client kubernetes.Interface
+
+	// Namespace and Name are the namespace and the name of the ConfigMap and the Secret.
+	Namespace string
+	Name      string
+
+	// ConfigDir and AuthDir are directories that the ConfigMap and the Secret are written into, respectively.
+	ConfigDir string
+	AuthDir   string
+
+	// OnChanged is called after the files have been changed.
+	OnChanged func() error
+
+	// RetryInterval is an interval to call OnChanged again after it fails.
+	// OnChanged is not retried if it is zero.
+	RetryInterval time.Duration
+
+	// Log is a logger.
+	Log logr.Logger
+}
+
+// Run watches the ConfigMap and the Secret until ctx is done.
+// Only the objects with the name are listed and watched, so that the server needs no access to the others.
+func (w *ConfigWatcher) Run(ctx context.Context) error {
+	factory := informers.NewSharedInformerFactoryWithOptions(w.Client, 0,
+		informers.WithNamespace(w.Namespace),
+		informers.WithTweakListOptions(func(opts *metav1.ListOptions) {
+			opts.FieldSelector = fields.OneTermEqualSelector("metadata.name", w.Name).String()
+		}),
+	)
+	configMaps := factory.Core().V1().ConfigMaps()
+	secrets := factory.Core().V1().Secrets()
+
+	// Changes are coalesced since the files are always written from the latest objects.
+	changes := make(chan struct{}, 1)
+	notify := func() {
+		select {
+		case changes <- struct{}{}:
+		default:
+		}
+	}
+	handler := cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(interface{}) { notify() },
+		UpdateFunc: func(interface{}, interface{}) { notify() },
+	}
+	for _, informer := range []cache.SharedIndexInformer{configMaps.Informer(), secrets.Informer()} {
+		if _, err := informer.AddEventHandler(handler); err != nil {
+			return err
+		}
+	}
+
+	factory.Start(ctx.Done())
+	defer factory.Shutdown()
+	for typ, synced := range factory.WaitForCacheSync(ctx.Done()) {
+		if !synced {
+			return fmt.Errorf("failed to sync the cache of %v", typ)
+		}
+	}
+
+	var retry <-chan time.Time
+	for {
+		var err error
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-changes:
+			err = w.sync(configMaps.Lister(), secrets.Lister())
+		case <-retry:
+			w.Log.Info("retrying to apply configuration")
+			err = w.OnChanged()
+		}
+		retry = nil
+		if err != nil {
+			w.Log.Error(err, "failed to apply configuration")
+			if w.RetryInterval > 0 {
+				retry = time.After(w.RetryInterval)
+			}
+		}
+	}
+}
+
+// sync writes the ConfigMap and the Secret into the directories, and calls OnChanged if any file has changed.
+func (w *ConfigWatcher) sync(configMaps corev1listers.ConfigMapLister, secrets corev1listers.SecretLister) error {
+	cm, err := configMaps.ConfigMaps(w.Namespace).Get(w.Name)
+	if apierrors.IsNotFound(err) {
+		w.Log.Info("waiting for the ConfigMap to be created", "name", w.Name)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	secret, err := secrets.Secrets(w.Namespace).Get(w.Name)
+	if apierrors.IsNotFound(err) {
+		w.Log.Info("waiting for the Secret to be created", "name", w.Name)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// The Secret is written first so that users exist before they are granted permissions.
+	authChanged, err := WriteFiles(w.AuthDir, secret.Data, 0600)
+	if err != nil {
+		return err
+	}
+	configFiles := make(map[string][]byte, len(cm.Data))
+	for k, v := range cm.Data {
+		configFiles[k] = []byte(v)
+	}
+	configChanged, err := WriteFiles(w.ConfigDir, configFiles, 0644)
+	if err != nil {
+		return err
+	}
+	if !authChanged && !configChanged {
+		return nil
+	}
+	w.Log.Info("detected config change", "configMapResourceVersion", cm.ResourceVersion, "secretResourceVersion", secret.ResourceVersion)
+	return w.OnChanged()
+}
@@ -0,0 +1,146 @@
+package serverupdater_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/markzhang0928/svn-operator/pkg/serverupdater"
+)
+
+var _ = Describe("WriteFiles", func() {
+	var dir string
+
+	BeforeEach(func() {
+		var err error
+		dir, err = os.MkdirTemp("", "serverupdater")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	It("replaces the files through the ..data symlink", func() {
+		Expect(serverupdater.WriteFiles(dir, map[string][]byte{"A": []byte("a"), "B": []byte("b")}, 0644)).To(BeTrue())
+		Expect(os.ReadFile(filepath.Join(dir, "A"))).To(BeEquivalentTo("a"))
+		Expect(os.ReadFile(filepath.Join(dir, "B"))).To(BeEquivalentTo("b"))
+		first, err := os.Readlink(filepath.Join(dir, "..data"))
+		Expect(err).NotTo(HaveOccurred())
+
+		By("writing the same files")
+		Expect(serverupdater.WriteFiles(dir, map[string][]byte{"A": []byte("a"), "B": []byte("b")}, 0644)).To(BeFalse())
+
+		By("changing a file and removing another")
+		Expect(serverupdater.WriteFiles(dir, map[string][]byte{"A": []byte("aa")}, 0644)).To(BeTrue())
+		Expect(os.ReadFile(filepath.Join(dir, "A"))).To(BeEquivalentTo("aa"))
+		Expect(filepath.Join(dir, "B")).NotTo(BeAnExistingFile())
+		Expect(filepath.Join(dir, first)).NotTo(BeADirectory())
+	})
+})
+
+var _ = Describe("ConfigWatcher", func() {
+	var dir string
+	var ctx context.Context
+	var cancel context.CancelFunc
+
+	BeforeEach(func() {
+		var err error
+		dir, err = os.MkdirTemp("", "serverupdater")
+		Expect(err).NotTo(HaveOccurred())
+		ctx, cancel = context.WithCancel(context.Background())
+	})
+
+	AfterEach(func() {
+		cancel()
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	It("retries OnChanged until it succeeds", func() {
+		meta := metav1.ObjectMeta{Namespace: "default", Name: "svn"}
+		client := fake.NewSimpleClientset(
+			&corev1.ConfigMap{ObjectMeta: meta, Data: map[string]string{"Repos": "repositories: []\n"}},
+			&corev1.Secret{ObjectMeta: meta, Data: map[string][]byte{"AuthUserFile": []byte("noel:hash\n")}},
+		)
+
+		var calls int32
+		w := &serverupdater.ConfigWatcher{
+			Client:    client,
+			Namespace: "default",
+			Name:      "svn",
+			ConfigDir: filepath.Join(dir, "config"),
+			AuthDir:   filepath.Join(dir, "auth"),
+			OnChanged: func() error {
+				if atomic.AddInt32(&calls, 1) == 1 {
+					return errors.New("apache is not ready")
+				}
+				return nil
+			},
+			RetryInterval: 10 * time.Millisecond,
+			Log:           logr.Discard(),
+		}
+		Expect(os.Mkdir(w.ConfigDir, 0755)).To(Succeed())
+		Expect(os.Mkdir(w.AuthDir, 0755)).To(Succeed())
+		go func() {
+			defer GinkgoRecover()
+			Expect(w.Run(ctx)).To(Succeed())
+		}()
+
+		Eventually(func() int32 { return atomic.LoadInt32(&calls) }).Should(BeEquivalentTo(2))
+		Consistently(func() int32 { return atomic.LoadInt32(&calls) }, "100ms").Should(BeEquivalentTo(2))
+	})
+
+	It("writes the ConfigMap and the Secret as soon as they change", func() {
+		meta := metav1.ObjectMeta{Namespace: "default", Name: "svn"}
+		cm := &corev1.ConfigMap{ObjectMeta: meta, Data: map[string]string{"Repos": "repositories: []\n"}}
+		secret := &corev1.Secret{ObjectMeta: meta, Data: map[string][]byte{"AuthUserFile": []byte("noel:hash\n")}}
+		client := fake.NewSimpleClientset(cm, secret)
+
+		var changes int32
+		w := &serverupdater.ConfigWatcher{
+			Client:    client,
+			Namespace: "default",
+			Name:      "svn",
+			ConfigDir: filepath.Join(dir, "config"),
+			AuthDir:   filepath.Join(dir, "auth"),
+			OnChanged: func() error {
+				atomic.AddInt32(&changes, 1)
+				return nil
+			},
+			Log: logr.Discard(),
+		}
+		Expect(os.Mkdir(w.ConfigDir, 0755)).To(Succeed())
+		Expect(os.Mkdir(w.AuthDir, 0755)).To(Succeed())
+		go func() {
+			defer GinkgoRecover()
+			Expect(w.Run(ctx)).To(Succeed())
+		}()
+
+		Eventually(func() ([]byte, error) {
+			return os.ReadFile(filepath.Join(w.AuthDir, "AuthUserFile"))
+		}).Should(BeEquivalentTo("noel:hash\n"))
+		Eventually(func() ([]byte, error) {
+			return os.ReadFile(filepath.Join(w.ConfigDir, "Repos"))
+		}).Should(BeEquivalentTo("repositories: []\n"))
+		Eventually(func() int32 { return atomic.LoadInt32(&changes) }).Should(BeEquivalentTo(1))
+
+		By("updating the ConfigMap")
+		cm.Data["Repos"] = "repositories:\n- name: hoge\n"
+		_, err := client.CoreV1().ConfigMaps("default").Update(ctx, cm, metav1.UpdateOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(func() ([]byte, error) {
+			return os.ReadFile(filepath.Join(w.ConfigDir, "Repos"))
+		}).Should(BeEquivalentTo("repositories:\n- name: hoge\n"))
+		Eventually(func() int32 { return atomic.LoadInt32(&changes) }).Should(BeEquivalentTo(2))
+	})
+})
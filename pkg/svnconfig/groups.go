@@ -0,0 +1,75 @@
+package svnconfig
+
+import (
+	"fmt"
+	"strings"
+)
+
+// InvalidGroups finds groups that must not be written into AuthzSVNAccessFile because mod_authz_svn
+// would reject them. Such groups are:
+//
+//   - groups that have unknown groups as their members,
+//   - groups that (directly or indirectly) contain themselves, and
+//   - groups that have any of the above as their members.
+//
+// It returns a map from the names of the invalid groups to the reasons why they are invalid.
+func InvalidGroups(groups []Group) map[string]error {
+	r := &groupResolver{
+		groups:  make(map[string]*Group, len(groups)),
+		visited: map[string]bool{},
+		invalid: map[string]error{},
+	}
+	for i := range groups {
+		r.groups[groups[i].Name] = &groups[i]
+	}
+	for i := range groups {
+		r.visit(groups[i].Name)
+	}
+	return r.invalid
+}
+
+type groupResolver struct {
+	groups  map[string]*Group
+	visited map[string]bool
+	invalid map[string]error
+
+	// path is a list of groups that are being visited.
+	path []string
+}
+
+func (r *groupResolver) visit(name string) error {
+	for i, n := range r.path {
+		if n == name {
+			cycle := append(append([]string{}, r.path[i:]...), name)
+			err := fmt.Errorf("groups form a cycle: %s", strings.Join(cycle, " -> "))
+			for _, m := range r.path[i:] {
+				if _, ok := r.invalid[m]; !ok {
+					r.invalid[m] = err
+				}
+			}
+			return err
+		}
+	}
+	if r.visited[name] {
+		return r.invalid[name]
+	}
+	r.visited[name] = true
+	r.path = append(r.path, name)
+	defer func() { r.path = r.path[:len(r.path)-1] }()
+
+	for _, sub := range r.groups[name].Groups {
+		var err error
+		if _, ok := r.groups[sub]; !ok {
+			err = fmt.Errorf("member group %q does not exist", sub)
+		} else if r.visit(sub) != nil {
+			err = fmt.Errorf("member group %q is invalid", sub)
+		}
+		if err != nil {
+			if _, ok := r.invalid[name]; !ok {
+				r.invalid[name] = err
+			}
+			break
+		}
+	}
+	return r.invalid[name]
+}
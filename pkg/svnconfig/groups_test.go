@@ -0,0 +1,77 @@
+package svnconfig_test
+
+import (
+	"github.com/markzhang0928/svn-operator/pkg/svnconfig"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("InvalidGroups", func() {
+	reasons := func(groups []svnconfig.Group) map[string]string {
+		result := map[string]string{}
+		for name, err := range svnconfig.InvalidGroups(groups) {
+			result[name] = err.Error()
+		}
+		return result
+	}
+
+	Context("when there is no member group", func() {
+		It("returns nothing", func() {
+			Expect(reasons([]svnconfig.Group{
+				{Name: "gen0", Users: []string{"sora"}},
+				{Name: "gen1", Users: []string{"mel"}},
+			})).To(BeEmpty())
+		})
+	})
+
+	Context("when member groups form a tree", func() {
+		It("returns nothing", func() {
+			Expect(reasons([]svnconfig.Group{
+				{Name: "hololive", Groups: []string{"jp", "en"}},
+				{Name: "jp", Groups: []string{"gen0"}},
+				{Name: "en", Groups: []string{"gen0"}},
+				{Name: "gen0", Users: []string{"sora"}},
+			})).To(BeEmpty())
+		})
+	})
+
+	Context("when a group refers to an unknown group", func() {
+		It("returns the group and its ancestors", func() {
+			Expect(reasons([]svnconfig.Group{
+				{Name: "hololive", Groups: []string{"jp"}},
+				{Name: "jp", Groups: []string{"gen99"}},
+				{Name: "en", Users: []string{"gura"}},
+			})).To(Equal(map[string]string{
+				"hololive": `member group "jp" is invalid`,
+				"jp":       `member group "gen99" does not exist`,
+			}))
+		})
+	})
+
+	Context("when a group contains itself", func() {
+		It("returns the group", func() {
+			Expect(reasons([]svnconfig.Group{
+				{Name: "ouroboros", Groups: []string{"ouroboros"}},
+			})).To(Equal(map[string]string{
+				"ouroboros": "groups form a cycle: ouroboros -> ouroboros",
+			}))
+		})
+	})
+
+	Context("when groups form a cycle", func() {
+		It("returns all groups in the cycle and their ancestors", func() {
+			Expect(reasons([]svnconfig.Group{
+				{Name: "root", Groups: []string{"a"}},
+				{Name: "a", Groups: []string{"b"}},
+				{Name: "b", Groups: []string{"c"}},
+				{Name: "c", Groups: []string{"a"}},
+				{Name: "other", Users: []string{"ina"}},
+			})).To(Equal(map[string]string{
+				"root": `member group "a" is invalid`,
+				"a":    "groups form a cycle: a -> b -> c -> a",
+				"b":    "groups form a cycle: a -> b -> c -> a",
+				"c":    "groups form a cycle: a -> b -> c -> a",
+			}))
+		})
+	})
+})
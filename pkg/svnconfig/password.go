@@ -0,0 +1,82 @@
+package svnconfig
+
+import (
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"regexp"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// BcryptCost is the cost of hashes computed by HashPassword, which is the same as `htpasswd -B`.
+const BcryptCost = 5
+
+// passwordHashPatterns match hashes in the formats that Apache supports on all platforms.
+//
+// See https://httpd.apache.org/docs/2.4/misc/password_encryptions.html for more details.
+var passwordHashPatterns = []*regexp.Regexp{
+	// bcrypt
+	regexp.MustCompile(`^\$2[aby]?\$[0-9]{2}\$[./A-Za-z0-9]{53}$`),
+	// MD5 (apr1)
+	regexp.MustCompile(`^\$apr1\$[./A-Za-z0-9]{1,8}\$[./A-Za-z0-9]{22}$`),
+	// SHA1
+	regexp.MustCompile(`^\{SHA\}[A-Za-z0-9+/]{27}=$`),
+}
+
+// ValidatePasswordHash returns an error if hash is not a bcrypt, apr1 or SHA1 hash computed by `htpasswd`.
+func ValidatePasswordHash(hash string) error {
+	for _, p := range passwordHashPatterns {
+		if p.MatchString(hash) {
+			return nil
+		}
+	}
+	return errors.New("password hash must be in bcrypt, apr1 or SHA1 format")
+}
+
+// HashPassword computes a bcrypt hash of the password that can be written into AuthUserFile.
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), BcryptCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// PasswordMatches returns true if hash is a bcrypt hash of the password.
+// Hashes in the other formats never match.
+func PasswordMatches(hash, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// ParseAuthUserFile parses AuthUserFile and returns a map from names of users to their hashes.
+// Malformed lines are ignored.
+func ParseAuthUserFile(content string) map[string]string {
+	hashes := map[string]string{}
+	for _, line := range strings.Split(content, "\n") {
+		name, hash, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if !ok || name == "" || hash == "" {
+			continue
+		}
+		hashes[name] = hash
+	}
+	return hashes
+}
+
+// passwordLetters are letters that generated passwords consist of.
+const passwordLetters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// GeneratePassword generates a random password of the given length.
+func GeneratePassword(length int) (string, error) {
+	max := big.NewInt(int64(len(passwordLetters)))
+	password := make([]byte, length)
+	for i := range password {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		password[i] = passwordLetters[n.Int64()]
+	}
+	return string(password), nil
+}
@@ -0,0 +1,59 @@
+package svnconfig_test
+
+import (
+	"github.com/markzhang0928/svn-operator/pkg/svnconfig"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Password", func() {
+	Describe("HashPassword", func() {
+		It("computes a bcrypt hash that matches the password", func() {
+			hash, err := svnconfig.HashPassword("himitsu")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(hash).To(HavePrefix("$2a$05$"))
+			Expect(svnconfig.PasswordMatches(hash, "himitsu")).To(BeTrue())
+			Expect(svnconfig.PasswordMatches(hash, "wrong")).To(BeFalse())
+		})
+	})
+
+	Describe("PasswordMatches", func() {
+		It("does not match hashes in other formats", func() {
+			Expect(svnconfig.PasswordMatches("{SHA}Mx7qAOAbnkR1ZKhGEmM+yd/ksPA=", "himitsu")).To(BeFalse())
+		})
+	})
+
+	Describe("ValidatePasswordHash", func() {
+		It("accepts bcrypt, apr1 and SHA1 hashes", func() {
+			Expect(svnconfig.ValidatePasswordHash("$2y$05$Z9loUIkf0DynjbD0UMEpneKCSKYfkTCaE/pwY8wt7MtKQILxKRwjG")).To(Succeed())
+			Expect(svnconfig.ValidatePasswordHash("$apr1$aMnPY4vZ$Ubr4HhWnuvY39pDvTXGcd/")).To(Succeed())
+			Expect(svnconfig.ValidatePasswordHash("{SHA}Mx7qAOAbnkR1ZKhGEmM+yd/ksPA=")).To(Succeed())
+		})
+
+		It("rejects the others", func() {
+			Expect(svnconfig.ValidatePasswordHash("himitsu")).NotTo(Succeed())
+			Expect(svnconfig.ValidatePasswordHash("$2y$05$tooshort")).NotTo(Succeed())
+			Expect(svnconfig.ValidatePasswordHash("$1$salt$hash")).NotTo(Succeed())
+		})
+	})
+
+	Describe("GeneratePassword", func() {
+		It("generates random alphanumeric passwords", func() {
+			p1, err := svnconfig.GeneratePassword(24)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(p1).To(MatchRegexp(`^[a-zA-Z0-9]{24}$`))
+			p2, err := svnconfig.GeneratePassword(24)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(p2).NotTo(Equal(p1))
+		})
+	})
+
+	Describe("ParseAuthUserFile", func() {
+		It("returns hashes of users", func() {
+			Expect(svnconfig.ParseAuthUserFile("sora:$2y$05$abc\nmel:{SHA}xyz\n\nbroken\n")).To(Equal(map[string]string{
+				"sora": "$2y$05$abc",
+				"mel":  "{SHA}xyz",
+			}))
+		})
+	})
+})
@@ -3,6 +3,10 @@ package svnconfig
 
 import (
 	"bytes"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
 	"text/template"
 
 	"sigs.k8s.io/yaml"
@@ -11,6 +15,12 @@ import (
 var (
 	tmplAuthzSVNAccessFile = template.Must(template.New("AuthzSVNAccessFile").Parse(rawTmplAuthzSVNAccessFile))
 	tmplAuthUserFile       = template.Must(template.New("AuthUserFile").Parse(rawTmplAuthUserFile))
+	tmplSvnserveConf       = template.Must(template.New("SvnserveConf").Parse(rawTmplSvnserveConf))
+	tmplSvnservePasswordDB = template.Must(template.New("SvnservePasswordDB").Parse(rawTmplSvnservePasswordDB))
+	tmplApacheConf         = template.Must(template.New("ApacheConf").Funcs(template.FuncMap{
+		"onOff":              onOff,
+		"repositoryLocation": repositoryLocation,
+	}).Parse(rawTmplApacheConf))
 )
 
 // Generator generates configuration files for SVN server.
@@ -21,32 +31,279 @@ type Generator struct {
 	Repositories []Repository
 	Groups       []Group
 	Users        []User
+
+	// Removals are repositories that are being deleted.
+	// They are not accessible and are removed by the server updater.
+	Removals []Removal
+
+	// Apache is the configuration of Apache that ApacheConf renders.
+	Apache Apache
+}
+
+// Apache is a configuration of Apache that serves repositories with mod_dav_svn.
+//
+// Optional directives are omitted if their fields are zero or nil, so that the defaults of Apache
+// or apache2.conf apply.
+type Apache struct {
+	// ServerName is the host name that Apache identifies itself with, e.g. in redirects.
+	ServerName string
+
+	// Timeout is the number of seconds that Apache waits for I/O.
+	Timeout int32
+
+	// KeepAlive enables persistent connections.
+	KeepAlive *bool
+
+	// KeepAliveTimeout is the number of seconds that Apache waits for the next request on a persistent connection.
+	KeepAliveTimeout int32
+
+	// InMemoryCacheSizeKB is the size in kilobytes of the cache of mod_dav_svn per process.
+	InMemoryCacheSizeKB *int64
+
+	// Location is the URL path that repositories are served under, e.g. "/repos/".
+	Location string
+
+	// ParentPath is the directory that contains repositories.
+	ParentPath string
+
+	// ListParentPath lets users list the repositories by accessing Location.
+	ListParentPath bool
+
+	// CompressionLevel is the level from 0 (no compression) to 9 of compression of the data sent to clients.
+	CompressionLevel *int32
+
+	// LimitXMLRequestBody is the maximum size in bytes of the XML bodies of requests. 0 means unlimited.
+	LimitXMLRequestBody *int64
+
+	// AuthUserFile is a path to AuthUserFile, which is used unless LDAP is set.
+	AuthUserFile string
+
+	// AuthzSVNAccessFile is a path to AuthzSVNAccessFile.
+	AuthzSVNAccessFile string
+
+	// LDAP makes Apache authenticate users with an LDAP directory instead of AuthUserFile.
+	LDAP *LDAP
+}
+
+// LDAP is a configuration of mod_authnz_ldap.
+type LDAP struct {
+	// URL is an AuthLDAPURL, e.g. "ldap://ldap.example.com/ou=people,dc=example,dc=com?uid?sub?(objectClass=person)".
+	URL string
+
+	// StartTLS upgrades connections to the LDAP server with STARTTLS.
+	StartTLS bool
+
+	// BindDN is a DN to bind as to search users. Apache binds anonymously if it is empty.
+	BindDN string
+
+	// BindPasswordFile is a path to a file that contains the password of BindDN.
+	BindPasswordFile string
+
+	// CACertFile is a path to a PEM file of CA certificates that verify the certificate of the LDAP server.
+	CACertFile string
 }
 
 // Repository is a definition of a repository.
 type Repository struct {
 	Name        string
 	Permissions []Permission
+
+	// Access is an access level for users that are not listed in Permissions.
+	// An empty access is equivalent to AccessPrivate.
+	Access string
+
+	// InitialLayout is a list of directories that are created right after the repository is created.
+	InitialLayout []string
+
+	// Source is a dump file that is loaded right after the repository is created.
+	Source *Source
+
+	// HTTP overrides the configuration of Apache for the repository.
+	// The repository is served with that of Apache.Location if it is nil.
+	HTTP *RepositoryHTTP
+}
+
+// RepositoryHTTP is a configuration of Apache for a specific repository.
+type RepositoryHTTP struct {
+	// Autoversioning lets WebDAV clients that are not aware of Subversion commit to the repository.
+	Autoversioning bool
+
+	// LimitRequestBody is the maximum size in bytes of the bodies of requests. 0 means unlimited.
+	LimitRequestBody *int64
+
+	// IndexXSLT is a URL of an XSL transformation that formats directory listings in web browsers.
+	IndexXSLT string
+
+	// AllowedSourceRanges are IP addresses and CIDRs that clients must connect from.
+	// Clients from anywhere are allowed if it is empty.
+	AllowedSourceRanges []string
+}
+
+// Source is a location of a dump file. Exactly one of File and URL must be set.
+type Source struct {
+	// File is a path to the dump file in the SVN server.
+	File string `json:"file,omitempty"`
+
+	// URL is an HTTP(S) URL of the dump file.
+	URL string `json:"url,omitempty"`
+}
+
+// Access levels of repositories.
+const (
+	AccessPrivate            = "private"
+	AccessAnonymousRead      = "anonymousRead"
+	AccessAuthenticatedRead  = "authenticatedRead"
+	AccessAuthenticatedWrite = "authenticatedWrite"
+)
+
+// Tokens that mod_authz_svn uses to match users by the way they are authenticated.
+const (
+	TokenAnonymous     = "$anonymous"
+	TokenAuthenticated = "$authenticated"
+)
+
+// AccessRules returns permissions granted to the whole repository according to its access level.
+func (r Repository) AccessRules() []Permission {
+	switch r.Access {
+	case AccessAnonymousRead:
+		return []Permission{
+			{User: TokenAnonymous, Permission: "r"},
+			{User: TokenAuthenticated, Permission: "r"},
+		}
+	case AccessAuthenticatedRead:
+		return []Permission{{User: TokenAuthenticated, Permission: "r"}}
+	case AccessAuthenticatedWrite:
+		return []Permission{{User: TokenAuthenticated, Permission: "rw"}}
+	default:
+		return nil
+	}
 }
 
 // Permission configurates permission to a specific repository.
+//
+// Exactly one of Group and User must be set.
 type Permission struct {
 	Group      string
+	User       string
 	Permission string
+
+	// Path is a path inside the repository that the permission applies to.
+	// An empty path is equivalent to RootPath.
+	Path string
+}
+
+// RootPath is a path that represents the whole repository.
+const RootPath = "/"
+
+// NormalizePath converts a path inside a repository into the canonical form used in
+// mod_authz_svn sections, e.g. "/trunk/" and "/branches/../trunk" become "/trunk".
+func NormalizePath(p string) string {
+	return path.Clean("/" + p)
+}
+
+// layoutDirectoryPattern matches relative paths that do not escape from the repository.
+var layoutDirectoryPattern = regexp.MustCompile(`^[a-zA-Z0-9_-][a-zA-Z0-9_.-]*(/[a-zA-Z0-9_-][a-zA-Z0-9_.-]*)*$`)
+
+// IsValidLayoutDirectory reports whether d is a relative path of a directory that can be
+// created as an initial layout, i.e. none of its segments is empty or starts with `.`.
+func IsValidLayoutDirectory(d string) bool {
+	return layoutDirectoryPattern.MatchString(d)
+}
+
+// Section is a set of permissions that apply to the same path in a repository.
+type Section struct {
+	Path        string
+	Permissions []Permission
+}
+
+// permissionRanks orders permissions from the least permissive to the most permissive one.
+var permissionRanks = map[string]int{
+	"":   0,
+	"r":  1,
+	"rw": 2,
+}
+
+// Sections groups the permissions of the repository by their paths.
+//
+// The section of RootPath always comes first and the others are sorted by their paths.
+// If a group or a user appears more than once in the same section, these entries are merged into
+// one that has the most permissive permission among them. Otherwise the permissions keep their order.
+func (r Repository) Sections() []Section {
+	indices := map[string]int{RootPath: 0}
+	sections := []Section{{Path: RootPath}}
+	for _, p := range r.Permissions {
+		if p.Path == "" {
+			p.Path = RootPath
+		}
+		i, ok := indices[p.Path]
+		if !ok {
+			i = len(sections)
+			indices[p.Path] = i
+			sections = append(sections, Section{Path: p.Path})
+		}
+		sections[i].Permissions = mergePermission(sections[i].Permissions, p)
+	}
+	rest := sections[1:]
+	sort.Slice(rest, func(i, j int) bool {
+		return rest[i].Path < rest[j].Path
+	})
+	return sections
+}
+
+func mergePermission(perms []Permission, p Permission) []Permission {
+	for i := range perms {
+		if perms[i].Group != p.Group || perms[i].User != p.User {
+			continue
+		}
+		if permissionRanks[p.Permission] > permissionRanks[perms[i].Permission] {
+			perms[i].Permission = p.Permission
+		}
+		return perms
+	}
+	return append(perms, p)
 }
 
 // Group is a definitions of a group.
 type Group struct {
 	Name  string
 	Users []string
+
+	// Groups is a list of groups whose members also belong to the group.
+	Groups []string
+}
+
+// Members returns users of the group followed by its member groups prefixed with '@'.
+func (g Group) Members() []string {
+	members := make([]string, 0, len(g.Users)+len(g.Groups))
+	members = append(members, g.Users...)
+	for _, sub := range g.Groups {
+		members = append(members, "@"+sub)
+	}
+	return members
 }
 
 // User is a definition of a user.
 type User struct {
 	Name              string
 	EncryptedPassword string
+
+	// Password is the plaintext password of the user, which is written into the password database of svnserve.
+	// It is empty if only the hash is known, in which case the user cannot be authenticated by svnserve.
+	Password string
+}
+
+// Removal is a request to remove a repository.
+type Removal struct {
+	Name   string
+	Policy string
 }
 
+// Policies to remove repositories.
+const (
+	RemovalArchive = "Archive"
+	RemovalDelete  = "Delete"
+)
+
 // ReposConfig is a special configuration structure that is used to create SVN repositories.
 type ReposConfig struct {
 	Repositories []RepoEntry `json:"repositories"`
@@ -55,6 +312,17 @@ type ReposConfig struct {
 // RepoEntry is an entry for SVN repository.
 type RepoEntry struct {
 	Name string `json:"name,omitempty"`
+
+	// Removal is set if the repository should be removed instead of created.
+	// The value is either RemovalArchive or RemovalDelete.
+	Removal string `json:"removal,omitempty"`
+
+	// InitialLayout is a list of directories that are committed as the first revision
+	// when the repository is created.
+	InitialLayout []string `json:"initialLayout,omitempty"`
+
+	// Source is a dump file that is loaded when the repository is created.
+	Source *Source `json:"source,omitempty"`
 }
 
 // AuthzSVNAccessFile is an authorization configuration file for mod_authz_svn.
@@ -79,6 +347,60 @@ func (g *Generator) AuthUserFile() (string, error) {
 	return buf.String(), nil
 }
 
+// SvnserveConf is a configuration file of svnserve, which is passed by `--config-file` and shared by all repositories.
+// The users are authenticated with the password database at passwordDB, and authorized with
+// the AuthzSVNAccessFile at authzDB so that svnserve enforces the same permissions as mod_authz_svn.
+//
+// See https://svnbook.red-bean.com/en/1.7/svn.serverconfig.svnserve.html for more details.
+func (g *Generator) SvnserveConf(passwordDB, authzDB string) (string, error) {
+	buf := bytes.NewBuffer(nil)
+	params := struct {
+		PasswordDB string
+		AuthzDB    string
+	}{PasswordDB: passwordDB, AuthzDB: authzDB}
+	if err := tmplSvnserveConf.Execute(buf, params); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// SvnservePasswordDB is a password database of svnserve, which contains plaintext passwords.
+// Users without plaintext passwords are omitted.
+func (g *Generator) SvnservePasswordDB() (string, error) {
+	buf := bytes.NewBuffer(nil)
+	if err := tmplSvnservePasswordDB.Execute(buf, g); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// ApacheConf is a configuration file of Apache, which is included by apache2.conf at the server level.
+//
+// See https://svnbook.red-bean.com/en/1.7/svn.serverconfig.httpd.html,
+// https://svn.apache.org/repos/asf/subversion/trunk/subversion/mod_dav_svn/ and
+// https://httpd.apache.org/docs/2.4/mod/mod_authnz_ldap.html for more details.
+func (g *Generator) ApacheConf() (string, error) {
+	buf := bytes.NewBuffer(nil)
+	if err := tmplApacheConf.Execute(buf, g); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// onOff formats a flag as a value of Apache directives.
+func onOff(b bool) string {
+	if b {
+		return "On"
+	}
+	return "Off"
+}
+
+// repositoryLocation returns a regular expression that matches the URL paths of the repository under location.
+// <LocationMatch> is used since <Location /repos/foo> also matches /repos/foobar.
+func repositoryLocation(location, name string) string {
+	return "^" + regexp.QuoteMeta(strings.TrimSuffix(location, "/")+"/"+name) + "(/|$)"
+}
+
 func (g *Generator) ReposConfig() (string, error) {
 	marshaled, err := yaml.Marshal(g.BuildReposConfig())
 	if err != nil {
@@ -90,7 +412,10 @@ func (g *Generator) ReposConfig() (string, error) {
 func (g *Generator) BuildReposConfig() *ReposConfig {
 	repos := []RepoEntry{}
 	for _, r := range g.Repositories {
-		repos = append(repos, RepoEntry{Name: r.Name})
+		repos = append(repos, RepoEntry{Name: r.Name, InitialLayout: r.InitialLayout, Source: r.Source})
+	}
+	for _, r := range g.Removals {
+		repos = append(repos, RepoEntry{Name: r.Name, Removal: r.Policy})
 	}
 	return &ReposConfig{Repositories: repos}
 }
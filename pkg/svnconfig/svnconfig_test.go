@@ -4,6 +4,7 @@ import (
 	"github.com/markzhang0928/svn-operator/pkg/svnconfig"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"k8s.io/utils/ptr"
 )
 
 var _ = Describe("Svnconfig", func() {
@@ -79,9 +80,9 @@ engen1 = gura, ame, ina, calli, kiara
 						Repositories: []svnconfig.Repository{},
 						Users:        []svnconfig.User{},
 						Groups: []svnconfig.Group{
-							{"gen4", []string{"coco", "watame", "kanata", "luna", "towa"}},
-							{"gen5", []string{"nene", "polka", "lamy", "botan", "aloe"}},
-							{"gen999", []string{}},
+							{Name: "gen4", Users: []string{"coco", "watame", "kanata", "luna", "towa"}},
+							{Name: "gen5", Users: []string{"nene", "polka", "lamy", "botan", "aloe"}},
+							{Name: "gen999", Users: []string{}},
 						},
 					}
 					Expect(render()).To(Equal(`
@@ -90,6 +91,29 @@ gen4 = coco, watame, kanata, luna, towa
 gen5 = nene, polka, lamy, botan, aloe
 gen999 = 
 
+`))
+				})
+			})
+		})
+
+		Describe("section [groups] with member groups", func() {
+			Context("when a group has member groups", func() {
+				It("generates member groups prefixed with '@' after users", func() {
+					config = &svnconfig.Generator{
+						Repositories: []svnconfig.Repository{},
+						Users:        []svnconfig.User{},
+						Groups: []svnconfig.Group{
+							{Name: "hololive", Users: []string{"sora"}, Groups: []string{"gen0", "gen1"}},
+							{Name: "gen0", Users: []string{"roboco", "miko"}},
+							{Name: "gen1", Users: []string{"mel", "fubuki"}},
+						},
+					}
+					Expect(render()).To(Equal(`
+[groups]
+hololive = sora, @gen0, @gen1
+gen0 = roboco, miko
+gen1 = mel, fubuki
+
 `))
 				})
 			})
@@ -100,9 +124,9 @@ gen999 =
 				It("drops all permissions", func() {
 					config = &svnconfig.Generator{
 						Repositories: []svnconfig.Repository{
-							{"therepo", []svnconfig.Permission{}}},
+							{Name: "therepo", Permissions: []svnconfig.Permission{}}},
 						Groups: []svnconfig.Group{
-							{"fams", []string{"fubuki", "ayame", "mio", "subaru"}}},
+							{Name: "fams", Users: []string{"fubuki", "ayame", "mio", "subaru"}}},
 						Users: []svnconfig.User{},
 					}
 					Expect(render()).To(Equal(`
@@ -119,11 +143,11 @@ fams = fubuki, ayame, mio, subaru
 				It("grants 'r' permission to the group", func() {
 					config = &svnconfig.Generator{
 						Repositories: []svnconfig.Repository{
-							{"therepo", []svnconfig.Permission{
-								{"smok", "r"},
+							{Name: "therepo", Permissions: []svnconfig.Permission{
+								{Group: "smok", Permission: "r"},
 							}}},
 						Groups: []svnconfig.Group{
-							{"smok", []string{"subaru", "mio", "okayu", "korone"}}},
+							{Name: "smok", Users: []string{"subaru", "mio", "okayu", "korone"}}},
 						Users: []svnconfig.User{},
 					}
 					Expect(render()).To(Equal(`
@@ -141,11 +165,11 @@ smok = subaru, mio, okayu, korone
 				It("grants 'rw' permission to the group", func() {
 					config = &svnconfig.Generator{
 						Repositories: []svnconfig.Repository{
-							{"therepo", []svnconfig.Permission{
-								{"idgen2", "rw"},
+							{Name: "therepo", Permissions: []svnconfig.Permission{
+								{Group: "idgen2", Permission: "rw"},
 							}}},
 						Groups: []svnconfig.Group{
-							{"idgen2", []string{"ollie", "anya", "reine"}}},
+							{Name: "idgen2", Users: []string{"ollie", "anya", "reine"}}},
 						Users: []svnconfig.User{},
 					}
 					Expect(render()).To(Equal(`
@@ -163,11 +187,11 @@ idgen2 = ollie, anya, reine
 				It("grants no permission to the group", func() {
 					config = &svnconfig.Generator{
 						Repositories: []svnconfig.Repository{
-							{"therepo", []svnconfig.Permission{
-								{"nenes", ""},
+							{Name: "therepo", Permissions: []svnconfig.Permission{
+								{Group: "nenes", Permission: ""},
 							}}},
 						Groups: []svnconfig.Group{
-							{"nenes", []string{"nenechi", "supernenechi", "hypernenechi"}}},
+							{Name: "nenes", Users: []string{"nenechi", "supernenechi", "hypernenechi"}}},
 						Users: []svnconfig.User{},
 					}
 					Expect(render()).To(Equal(`
@@ -185,13 +209,13 @@ nenes = nenechi, supernenechi, hypernenechi
 				It("grants corresponding permissions respectively", func() {
 					config = &svnconfig.Generator{
 						Repositories: []svnconfig.Repository{
-							{"therepo", []svnconfig.Permission{
-								{"board", "r"},
-								{"mountains", "rw"},
+							{Name: "therepo", Permissions: []svnconfig.Permission{
+								{Group: "board", Permission: "r"},
+								{Group: "mountains", Permission: "rw"},
 							}}},
 						Groups: []svnconfig.Group{
-							{"board", []string{"shion", "rushia", "kanata", "gura"}},
-							{"mountains", []string{"choco", "noel", "coco"}}},
+							{Name: "board", Users: []string{"shion", "rushia", "kanata", "gura"}},
+							{Name: "mountains", Users: []string{"choco", "noel", "coco"}}},
 						Users: []svnconfig.User{},
 					}
 					Expect(render()).To(Equal(`
@@ -211,24 +235,24 @@ mountains = choco, noel, coco
 				It("generates list of repositories and its permissions", func() {
 					config = &svnconfig.Generator{
 						Repositories: []svnconfig.Repository{
-							{"therepo1", []svnconfig.Permission{
-								{"edible", "r"},
+							{Name: "therepo1", Permissions: []svnconfig.Permission{
+								{Group: "edible", Permission: "r"},
 							}},
-							{"therepo2", []svnconfig.Permission{
-								{"edible", "rw"},
-								{"carnivore", "r"},
+							{Name: "therepo2", Permissions: []svnconfig.Permission{
+								{Group: "edible", Permission: "rw"},
+								{Group: "carnivore", Permission: "r"},
 							}},
-							{"therepo3", []svnconfig.Permission{
-								{"edible", ""},
-								{"carnivore", "r"},
+							{Name: "therepo3", Permissions: []svnconfig.Permission{
+								{Group: "edible", Permission: ""},
+								{Group: "carnivore", Permission: "r"},
 							}},
-							{"therepo4", []svnconfig.Permission{
-								{"carnivore", "rw"},
+							{Name: "therepo4", Permissions: []svnconfig.Permission{
+								{Group: "carnivore", Permission: "rw"},
 							}},
 						},
 						Groups: []svnconfig.Group{
-							{"edible", []string{"watame", "ina", "kiara"}},
-							{"carnivore", []string{"botan", "gura"}}},
+							{Name: "edible", Users: []string{"watame", "ina", "kiara"}},
+							{Name: "carnivore", Users: []string{"botan", "gura"}}},
 						Users: []svnconfig.User{},
 					}
 					Expect(render()).To(Equal(`
@@ -250,6 +274,248 @@ carnivore = botan, gura
 * = 
 @carnivore = rw
 
+`))
+				})
+			})
+		})
+
+		Describe("access levels", func() {
+			var access string
+			BeforeEach(func() {
+				config = &svnconfig.Generator{
+					Repositories: []svnconfig.Repository{
+						{Name: "therepo", Permissions: []svnconfig.Permission{
+							{Group: "myth", Permission: "rw"},
+						}}},
+					Groups: []svnconfig.Group{
+						{Name: "myth", Users: []string{"gura", "calli"}}},
+					Users: []svnconfig.User{},
+				}
+			})
+			JustBeforeEach(func() {
+				config.Repositories[0].Access = access
+			})
+
+			Context("when the repository is private", func() {
+				BeforeEach(func() { access = svnconfig.AccessPrivate })
+
+				It("grants no permission to others", func() {
+					Expect(render()).To(Equal(`
+[groups]
+myth = gura, calli
+[therepo:/]
+* = 
+@myth = rw
+
+`))
+				})
+			})
+
+			Context("when anonymous users can read the repository", func() {
+				BeforeEach(func() { access = svnconfig.AccessAnonymousRead })
+
+				It("grants 'r' permission to both anonymous and authenticated users", func() {
+					Expect(render()).To(Equal(`
+[groups]
+myth = gura, calli
+[therepo:/]
+* = 
+$anonymous = r
+$authenticated = r
+@myth = rw
+
+`))
+				})
+			})
+
+			Context("when authenticated users can read the repository", func() {
+				BeforeEach(func() { access = svnconfig.AccessAuthenticatedRead })
+
+				It("grants 'r' permission to authenticated users", func() {
+					Expect(render()).To(Equal(`
+[groups]
+myth = gura, calli
+[therepo:/]
+* = 
+$authenticated = r
+@myth = rw
+
+`))
+				})
+			})
+
+			Context("when authenticated users can write to the repository", func() {
+				BeforeEach(func() { access = svnconfig.AccessAuthenticatedWrite })
+
+				It("grants 'rw' permission to authenticated users", func() {
+					Expect(render()).To(Equal(`
+[groups]
+myth = gura, calli
+[therepo:/]
+* = 
+$authenticated = rw
+@myth = rw
+
+`))
+				})
+			})
+		})
+
+		Describe("permissions granted to users", func() {
+			Context("when a user has a permission", func() {
+				It("grants the permission to the user without '@'", func() {
+					config = &svnconfig.Generator{
+						Repositories: []svnconfig.Repository{
+							{Name: "therepo", Permissions: []svnconfig.Permission{
+								{User: "kronii", Permission: "rw"},
+							}}},
+						Groups: []svnconfig.Group{},
+						Users:  []svnconfig.User{},
+					}
+					Expect(render()).To(Equal(`
+[groups]
+[therepo:/]
+* = 
+kronii = rw
+
+`))
+				})
+			})
+
+			Context("when both groups and users have permissions", func() {
+				It("generates lines of groups and users in the given order", func() {
+					config = &svnconfig.Generator{
+						Repositories: []svnconfig.Repository{
+							{Name: "therepo", Permissions: []svnconfig.Permission{
+								{Group: "council", Permission: "r"},
+								{User: "kronii", Permission: "rw"},
+								{User: "mumei", Permission: "r", Path: "/trunk"},
+							}}},
+						Groups: []svnconfig.Group{
+							{Name: "council", Users: []string{"kronii", "mumei", "fauna"}}},
+						Users: []svnconfig.User{},
+					}
+					Expect(render()).To(Equal(`
+[groups]
+council = kronii, mumei, fauna
+[therepo:/]
+* = 
+@council = r
+kronii = rw
+[therepo:/trunk]
+mumei = r
+
+`))
+				})
+			})
+
+			Context("when a user and a group have the same name", func() {
+				It("does not merge their permissions", func() {
+					config = &svnconfig.Generator{
+						Repositories: []svnconfig.Repository{
+							{Name: "therepo", Permissions: []svnconfig.Permission{
+								{Group: "bae", Permission: "r"},
+								{User: "bae", Permission: "rw"},
+								{User: "bae", Permission: "r"},
+							}}},
+						Groups: []svnconfig.Group{
+							{Name: "bae", Users: []string{"bae"}}},
+						Users: []svnconfig.User{},
+					}
+					Expect(render()).To(Equal(`
+[groups]
+bae = bae
+[therepo:/]
+* = 
+@bae = r
+bae = rw
+
+`))
+				})
+			})
+		})
+
+		Describe("section [REPO_NAME:PATH]", func() {
+			Context("when a permission is restricted to a path", func() {
+				It("generates a section for the path", func() {
+					config = &svnconfig.Generator{
+						Repositories: []svnconfig.Repository{
+							{Name: "therepo", Permissions: []svnconfig.Permission{
+								{Group: "qa", Permission: "r", Path: "/trunk"},
+								{Group: "dev", Permission: "rw"},
+							}}},
+						Groups: []svnconfig.Group{
+							{Name: "qa", Users: []string{"pekora", "marine"}},
+							{Name: "dev", Users: []string{"miko", "suisei"}}},
+						Users: []svnconfig.User{},
+					}
+					Expect(render()).To(Equal(`
+[groups]
+qa = pekora, marine
+dev = miko, suisei
+[therepo:/]
+* = 
+@dev = rw
+[therepo:/trunk]
+@qa = r
+
+`))
+				})
+			})
+
+			Context("when permissions are given to more than one paths", func() {
+				It("sorts sections by their paths", func() {
+					config = &svnconfig.Generator{
+						Repositories: []svnconfig.Repository{
+							{Name: "therepo", Permissions: []svnconfig.Permission{
+								{Group: "qa", Permission: "rw", Path: "/branches/qa"},
+								{Group: "qa", Permission: "r", Path: "/trunk"},
+								{Group: "dev", Permission: "rw", Path: "/"},
+								{Group: "dev", Permission: "", Path: "/branches/qa"},
+							}}},
+						Groups: []svnconfig.Group{
+							{Name: "qa", Users: []string{"pekora"}},
+							{Name: "dev", Users: []string{"miko"}}},
+						Users: []svnconfig.User{},
+					}
+					Expect(render()).To(Equal(`
+[groups]
+qa = pekora
+dev = miko
+[therepo:/]
+* = 
+@dev = rw
+[therepo:/branches/qa]
+@qa = rw
+@dev = 
+[therepo:/trunk]
+@qa = r
+
+`))
+				})
+			})
+
+			Context("when a group has more than one permissions to the same path", func() {
+				It("merges them into the most permissive one", func() {
+					config = &svnconfig.Generator{
+						Repositories: []svnconfig.Repository{
+							{Name: "therepo", Permissions: []svnconfig.Permission{
+								{Group: "qa", Permission: "r", Path: "/trunk"},
+								{Group: "qa", Permission: "rw", Path: "/trunk"},
+								{Group: "qa", Permission: "", Path: "/trunk"},
+							}}},
+						Groups: []svnconfig.Group{
+							{Name: "qa", Users: []string{"pekora"}}},
+						Users: []svnconfig.User{},
+					}
+					Expect(render()).To(Equal(`
+[groups]
+qa = pekora
+[therepo:/]
+* = 
+[therepo:/trunk]
+@qa = rw
+
 `))
 				})
 			})
@@ -283,12 +549,23 @@ carnivore = botan, gura
 					Repositories: []svnconfig.Repository{},
 					Groups:       []svnconfig.Group{},
 					Users: []svnconfig.User{
-						{"noel", "$2y$05$dM0mTvqGl8UqFgFY5CPxjO8jhqSntgSDlZeQK1XDwDKc2advIxEh6"},
+						{Name: "noel", EncryptedPassword: "$2y$05$dM0mTvqGl8UqFgFY5CPxjO8jhqSntgSDlZeQK1XDwDKc2advIxEh6"},
 					},
 				}
 				Expect(render()).To(Equal(`
 noel:$2y$05$dM0mTvqGl8UqFgFY5CPxjO8jhqSntgSDlZeQK1XDwDKc2advIxEh6
 
+`))
+			})
+		})
+
+		Context("when users have no hashes", func() {
+			It("omits them", func() {
+				config = &svnconfig.Generator{
+					Users: []svnconfig.User{{Name: "noel"}},
+				}
+				Expect(render()).To(Equal(`
+
 `))
 			})
 		})
@@ -299,8 +576,8 @@ noel:$2y$05$dM0mTvqGl8UqFgFY5CPxjO8jhqSntgSDlZeQK1XDwDKc2advIxEh6
 					Repositories: []svnconfig.Repository{},
 					Groups:       []svnconfig.Group{},
 					Users: []svnconfig.User{
-						{"noel", "$2y$05$dM0mTvqGl8UqFgFY5CPxjO8jhqSntgSDlZeQK1XDwDKc2advIxEh6"},
-						{"coco", "$2y$05$Vfm5k2KgyNIGMjoML44UNOXg1v2J7EqpeonrX8uuILRF9Oho/YLPy"},
+						{Name: "noel", EncryptedPassword: "$2y$05$dM0mTvqGl8UqFgFY5CPxjO8jhqSntgSDlZeQK1XDwDKc2advIxEh6"},
+						{Name: "coco", EncryptedPassword: "$2y$05$Vfm5k2KgyNIGMjoML44UNOXg1v2J7EqpeonrX8uuILRF9Oho/YLPy"},
 					},
 				}
 				Expect(render()).To(Equal(`
@@ -312,6 +589,201 @@ coco:$2y$05$Vfm5k2KgyNIGMjoML44UNOXg1v2J7EqpeonrX8uuILRF9Oho/YLPy
 		})
 	})
 
+	Describe("ApacheConf", func() {
+		apache := svnconfig.Apache{
+			Location:           "/repos/",
+			ParentPath:         "/svn/repos",
+			AuthUserFile:       "/etc/svn-auth/AuthUserFile",
+			AuthzSVNAccessFile: "/etc/svn-config/AuthzSVNAccessFile",
+		}
+		render := func(a svnconfig.Apache) string {
+			config := &svnconfig.Generator{Apache: a}
+			result, err := config.ApacheConf()
+			Expect(err).NotTo(HaveOccurred())
+			return result
+		}
+
+		Context("when LDAP is not set", func() {
+			It("authenticates users with AuthUserFile", func() {
+				Expect(render(apache)).To(Equal(`
+<Location /repos/>
+  DAV svn
+  SVNParentPath /svn/repos
+  AuthType Basic
+  AuthName "SVN Server"
+  AuthUserFile /etc/svn-auth/AuthUserFile
+  AuthzSVNAccessFile /etc/svn-config/AuthzSVNAccessFile
+  # Try anonymous access first and fall back to authentication if the
+  # AuthzSVNAccessFile does not allow anonymous users to access to the path.
+  Satisfy Any
+  Require valid-user
+</Location>
+`))
+			})
+		})
+
+		Context("when the server is tuned", func() {
+			It("renders the directives", func() {
+				a := apache
+				a.ServerName = "svn.example.com"
+				a.Timeout = 600
+				a.KeepAlive = ptr.To(false)
+				a.KeepAliveTimeout = 15
+				a.InMemoryCacheSizeKB = ptr.To[int64](65536)
+				a.Location = "/svn/"
+				a.ListParentPath = true
+				a.CompressionLevel = ptr.To[int32](0)
+				a.LimitXMLRequestBody = ptr.To[int64](0)
+				Expect(render(a)).To(Equal(`
+ServerName svn.example.com
+Timeout 600
+KeepAlive Off
+KeepAliveTimeout 15
+SVNInMemoryCacheSize 65536
+<Location /svn/>
+  DAV svn
+  SVNParentPath /svn/repos
+  SVNListParentPath On
+  SVNCompressionLevel 0
+  LimitXMLRequestBody 0
+  AuthType Basic
+  AuthName "SVN Server"
+  AuthUserFile /etc/svn-auth/AuthUserFile
+  AuthzSVNAccessFile /etc/svn-config/AuthzSVNAccessFile
+  # Try anonymous access first and fall back to authentication if the
+  # AuthzSVNAccessFile does not allow anonymous users to access to the path.
+  Satisfy Any
+  Require valid-user
+</Location>
+`))
+			})
+		})
+
+		Context("when repositories override the configuration", func() {
+			It("generates a location per repository with overrides", func() {
+				config := &svnconfig.Generator{
+					Apache: apache,
+					Repositories: []svnconfig.Repository{
+						{Name: "plain"},
+						{Name: "docs.web", HTTP: &svnconfig.RepositoryHTTP{
+							Autoversioning:   true,
+							LimitRequestBody: ptr.To[int64](0),
+							IndexXSLT:        "/svnindex.xsl",
+						}},
+						{Name: "secret", HTTP: &svnconfig.RepositoryHTTP{
+							AllowedSourceRanges: []string{"10.0.0.0/8", "192.168.1.10"},
+						}},
+					},
+				}
+				result, err := config.ApacheConf()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result).To(HaveSuffix(`
+  Require valid-user
+</Location>
+<LocationMatch "^/repos/docs\.web(/|$)">
+  SVNAutoversioning On
+  LimitRequestBody 0
+  SVNIndexXSLT "/svnindex.xsl"
+</LocationMatch>
+<LocationMatch "^/repos/secret(/|$)">
+  # Clients from the other addresses are denied even if anonymous users are allowed by "Satisfy Any".
+  <If "! -R '10.0.0.0/8' && ! -R '192.168.1.10'">
+    Satisfy All
+    Require all denied
+  </If>
+</LocationMatch>
+`))
+			})
+
+			It("denies anonymous access from addresses out of the allowed ranges", func() {
+				config := &svnconfig.Generator{
+					Apache: apache,
+					Repositories: []svnconfig.Repository{
+						{Name: "secret", HTTP: &svnconfig.RepositoryHTTP{AllowedSourceRanges: []string{"10.0.0.0/8"}}},
+					},
+				}
+				result, err := config.ApacheConf()
+				Expect(err).NotTo(HaveOccurred())
+				// "Require ip" would be skipped for anonymous users by "Satisfy Any" of the parent location,
+				// so the other addresses must be switched to "Satisfy All" before they are denied.
+				Expect(result).NotTo(ContainSubstring("Require ip"))
+				Expect(result).To(ContainSubstring(`
+  <If "! -R '10.0.0.0/8'">
+    Satisfy All
+    Require all denied
+  </If>
+`))
+			})
+		})
+
+		Context("when LDAP is set", func() {
+			It("authenticates users with mod_authnz_ldap", func() {
+				a := apache
+				a.LDAP = &svnconfig.LDAP{
+					URL:              "ldap://ldap.example.com/ou=people,dc=example,dc=com?uid?sub?(objectClass=person)",
+					StartTLS:         true,
+					BindDN:           "cn=svn,dc=example,dc=com",
+					BindPasswordFile: "/etc/svn-ldap/bind/bindPassword",
+					CACertFile:       "/etc/svn-ldap/ca/ca.crt",
+				}
+				Expect(render(a)).To(Equal(`
+LDAPTrustedGlobalCert CA_BASE64 /etc/svn-ldap/ca/ca.crt
+<Location /repos/>
+  DAV svn
+  SVNParentPath /svn/repos
+  AuthType Basic
+  AuthName "SVN Server"
+  AuthBasicProvider ldap
+  AuthLDAPURL "ldap://ldap.example.com/ou=people,dc=example,dc=com?uid?sub?(objectClass=person)" STARTTLS
+  AuthLDAPBindDN "cn=svn,dc=example,dc=com"
+  AuthLDAPBindPassword "exec:/bin/cat /etc/svn-ldap/bind/bindPassword"
+  AuthzSVNAccessFile /etc/svn-config/AuthzSVNAccessFile
+  # Try anonymous access first and fall back to authentication if the
+  # AuthzSVNAccessFile does not allow anonymous users to access to the path.
+  Satisfy Any
+  Require valid-user
+</Location>
+`))
+			})
+		})
+	})
+
+	Describe("SvnserveConf", func() {
+		It("refers to the password database and AuthzSVNAccessFile", func() {
+			config := &svnconfig.Generator{}
+			result, err := config.SvnserveConf("/etc/svn-auth/SvnservePasswordDB", "/etc/svn-config/AuthzSVNAccessFile")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal(`
+[general]
+anon-access = write
+auth-access = write
+password-db = /etc/svn-auth/SvnservePasswordDB
+authz-db = /etc/svn-config/AuthzSVNAccessFile
+realm = SVN Server
+`))
+		})
+	})
+
+	Describe("SvnservePasswordDB", func() {
+		It("generates entries of users with plaintext passwords", func() {
+			config := &svnconfig.Generator{
+				Users: []svnconfig.User{
+					{Name: "noel", EncryptedPassword: "$2y$05$dM0mTvqGl8UqFgFY5CPxjO8jhqSntgSDlZeQK1XDwDKc2advIxEh6", Password: "himitsu"},
+					{Name: "coco", EncryptedPassword: "$2y$05$Vfm5k2KgyNIGMjoML44UNOXg1v2J7EqpeonrX8uuILRF9Oho/YLPy"},
+					{Name: "watame", EncryptedPassword: "$2y$05$Vfm5k2KgyNIGMjoML44UNOXg1v2J7EqpeonrX8uuILRF9Oho/YLPy", Password: "wata"},
+				},
+			}
+			result, err := config.SvnservePasswordDB()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal(`
+[users]
+noel = himitsu
+watame = wata
+
+`))
+		})
+	})
+
 	Describe("ReposConfig", func() {
 		var config *svnconfig.Generator
 		render := func() string {
@@ -336,17 +808,105 @@ coco:$2y$05$Vfm5k2KgyNIGMjoML44UNOXg1v2J7EqpeonrX8uuILRF9Oho/YLPy
 			It("returns a list of repository names", func() {
 				config = &svnconfig.Generator{
 					Repositories: []svnconfig.Repository{
-						{"hoge", nil},
-						{"fuga", nil},
+						{Name: "hoge"},
+						{Name: "fuga"},
+					},
+					Groups: []svnconfig.Group{},
+					Users:  []svnconfig.User{},
+				}
+				Expect(render()).To(Equal(`repositories:
+- name: hoge
+- name: fuga
+`))
+			})
+		})
+
+		Context("when repositories have initial layouts", func() {
+			It("returns the layouts", func() {
+				config = &svnconfig.Generator{
+					Repositories: []svnconfig.Repository{
+						{Name: "hoge", InitialLayout: []string{"trunk", "branches", "tags"}},
+						{Name: "fuga"},
+					},
+					Groups: []svnconfig.Group{},
+					Users:  []svnconfig.User{},
+				}
+				Expect(render()).To(Equal(`repositories:
+- initialLayout:
+  - trunk
+  - branches
+  - tags
+  name: hoge
+- name: fuga
+`))
+			})
+		})
+
+		Context("when repositories have sources", func() {
+			It("returns the sources", func() {
+				config = &svnconfig.Generator{
+					Repositories: []svnconfig.Repository{
+						{Name: "hoge", Source: &svnconfig.Source{File: "/etc/svn-sources/hoge/dump"}},
+						{Name: "fuga", Source: &svnconfig.Source{URL: "https://example.com/fuga.dump"}},
 					},
 					Groups: []svnconfig.Group{},
 					Users:  []svnconfig.User{},
 				}
 				Expect(render()).To(Equal(`repositories:
 - name: hoge
+  source:
+    file: /etc/svn-sources/hoge/dump
 - name: fuga
+  source:
+    url: https://example.com/fuga.dump
 `))
 			})
 		})
+
+		Context("when some repositories are being removed", func() {
+			It("returns them with their removal policies", func() {
+				config = &svnconfig.Generator{
+					Repositories: []svnconfig.Repository{
+						{Name: "hoge"},
+					},
+					Groups: []svnconfig.Group{},
+					Users:  []svnconfig.User{},
+					Removals: []svnconfig.Removal{
+						{Name: "fuga", Policy: svnconfig.RemovalArchive},
+						{Name: "piyo", Policy: svnconfig.RemovalDelete},
+					},
+				}
+				Expect(render()).To(Equal(`repositories:
+- name: hoge
+- name: fuga
+  removal: Archive
+- name: piyo
+  removal: Delete
+`))
+			})
+		})
+	})
+
+	Describe("NormalizePath", func() {
+		It("returns the canonical form of the path", func() {
+			Expect(svnconfig.NormalizePath("")).To(Equal("/"))
+			Expect(svnconfig.NormalizePath("/trunk/")).To(Equal("/trunk"))
+			Expect(svnconfig.NormalizePath("/branches/../trunk")).To(Equal("/trunk"))
+		})
+	})
+
+	Describe("IsValidLayoutDirectory", func() {
+		It("accepts relative paths inside the repository", func() {
+			Expect(svnconfig.IsValidLayoutDirectory("trunk")).To(BeTrue())
+			Expect(svnconfig.IsValidLayoutDirectory("branches/qa-1.0")).To(BeTrue())
+		})
+
+		It("rejects the others", func() {
+			Expect(svnconfig.IsValidLayoutDirectory("")).To(BeFalse())
+			Expect(svnconfig.IsValidLayoutDirectory("/trunk")).To(BeFalse())
+			Expect(svnconfig.IsValidLayoutDirectory("branches/")).To(BeFalse())
+			Expect(svnconfig.IsValidLayoutDirectory("../trunk")).To(BeFalse())
+			Expect(svnconfig.IsValidLayoutDirectory("trunk/.svn")).To(BeFalse())
+		})
 	})
 })
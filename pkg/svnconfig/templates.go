@@ -3,22 +3,133 @@ package svnconfig
 const rawTmplAuthzSVNAccessFile = `
 [groups]
 {{ range $gi, $g := .Groups -}}
-{{- $g.Name }} = {{ range $ui, $u := $g.Users -}}
-{{- if gt $ui 0 -}}, {{ end -}}
-{{- $u -}}
-{{- end -}}{{/* $g.Users */}}
+{{- $g.Name }} = {{ range $mi, $m := $g.Members -}}
+{{- if gt $mi 0 -}}, {{ end -}}
+{{- $m -}}
+{{- end -}}{{/* $g.Members */}}
 {{ end -}}{{/* .Groups */}}
 {{- range $ri, $r := .Repositories -}}
-[{{- $r.Name -}}:/]
+{{- range $si, $s := $r.Sections -}}
+[{{- $r.Name -}}:{{- $s.Path -}}]
+{{ if eq $si 0 -}}
 * = 
-{{ range $pi, $p := $r.Permissions -}}
+{{ range $ai, $a := $r.AccessRules -}}
+{{- $a.User }} = {{ $a.Permission }}
+{{ end -}}
+{{ end -}}
+{{- range $pi, $p := $s.Permissions -}}
+{{- if $p.Group -}}
 @{{- $p.Group }} = {{ $p.Permission }}
+{{ else -}}
+{{- $p.User }} = {{ $p.Permission }}
 {{ end -}}
+{{- end -}}
+{{- end -}}{{/* $r.Sections */}}
 {{- end -}}{{/* .Repositories */}}
 `
 
 const rawTmplAuthUserFile = `
 {{ range $ui, $u := .Users -}}
+{{- if $u.EncryptedPassword -}}
 {{- $u.Name}}:{{- $u.EncryptedPassword }}
-{{ end -}}{{/* .Users */}}
+{{ end -}}
+{{- end -}}{{/* .Users */}}
+`
+
+// Access to svnserve is limited only by AuthzSVNAccessFile, as mod_authz_svn does with "Satisfy Any".
+const rawTmplSvnserveConf = `
+[general]
+anon-access = write
+auth-access = write
+password-db = {{ .PasswordDB }}
+authz-db = {{ .AuthzDB }}
+realm = SVN Server
+`
+
+const rawTmplSvnservePasswordDB = `
+[users]
+{{ range $ui, $u := .Users -}}
+{{- if $u.Password -}}
+{{- $u.Name }} = {{ $u.Password }}
+{{ end -}}
+{{- end -}}{{/* .Users */}}
+`
+
+// AllowedSourceRanges are not enforced with <RequireAll> and "Require ip", since "Satisfy Any" skips all Require
+// directives once mod_authz_svn has granted anonymous access in the access checker phase. Instead, <If> switches
+// clients from the other addresses to "Satisfy All", so that "Require all denied" rejects them whether they
+// are anonymous or not, while clients in the ranges keep anonymous access.
+const rawTmplApacheConf = `
+{{- with .Apache -}}
+{{- with .ServerName }}
+ServerName {{ . }}
+{{- end }}
+{{- with .Timeout }}
+Timeout {{ . }}
+{{- end }}
+{{- with .KeepAlive }}
+KeepAlive {{ onOff . }}
+{{- end }}
+{{- with .KeepAliveTimeout }}
+KeepAliveTimeout {{ . }}
+{{- end }}
+{{- with .InMemoryCacheSizeKB }}
+SVNInMemoryCacheSize {{ . }}
+{{- end }}
+{{- if and .LDAP .LDAP.CACertFile }}
+LDAPTrustedGlobalCert CA_BASE64 {{ .LDAP.CACertFile }}
+{{- end }}
+<Location {{ .Location }}>
+  DAV svn
+  SVNParentPath {{ .ParentPath }}
+{{- if .ListParentPath }}
+  SVNListParentPath On
+{{- end }}
+{{- with .CompressionLevel }}
+  SVNCompressionLevel {{ . }}
+{{- end }}
+{{- with .LimitXMLRequestBody }}
+  LimitXMLRequestBody {{ . }}
+{{- end }}
+  AuthType Basic
+  AuthName "SVN Server"
+{{- if .LDAP }}
+  AuthBasicProvider ldap
+  AuthLDAPURL "{{ .LDAP.URL }}"{{ if .LDAP.StartTLS }} STARTTLS{{ end }}
+{{- if .LDAP.BindDN }}
+  AuthLDAPBindDN "{{ .LDAP.BindDN }}"
+  AuthLDAPBindPassword "exec:/bin/cat {{ .LDAP.BindPasswordFile }}"
+{{- end }}
+{{- else }}
+  AuthUserFile {{ .AuthUserFile }}
+{{- end }}
+  AuthzSVNAccessFile {{ .AuthzSVNAccessFile }}
+  # Try anonymous access first and fall back to authentication if the
+  # AuthzSVNAccessFile does not allow anonymous users to access to the path.
+  Satisfy Any
+  Require valid-user
+</Location>
+{{- range $r := $.Repositories }}
+{{- with $r.HTTP }}
+<LocationMatch "{{ repositoryLocation $.Apache.Location $r.Name }}">
+{{- if .Autoversioning }}
+  SVNAutoversioning On
+{{- end }}
+{{- with .LimitRequestBody }}
+  LimitRequestBody {{ . }}
+{{- end }}
+{{- with .IndexXSLT }}
+  SVNIndexXSLT "{{ . }}"
+{{- end }}
+{{- with .AllowedSourceRanges }}
+  # Clients from the other addresses are denied even if anonymous users are allowed by "Satisfy Any".
+  <If "{{ range $i, $cidr := . }}{{ if $i }} && {{ end }}! -R '{{ $cidr }}'{{ end }}">
+    Satisfy All
+    Require all denied
+  </If>
+{{- end }}
+</LocationMatch>
+{{- end }}
+{{- end }}
+{{ end -}}
 `